@@ -0,0 +1,305 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of the batching used by the exporting handlers.
+const (
+	DefaultBatchSize    = 512
+	DefaultBatchAge     = time.Second
+	DefaultQueueSize    = 8192
+	DefaultRetries      = 5
+	DefaultBackoff      = 100 * time.Millisecond
+	DefaultMaxBackoff   = 10 * time.Second
+	DefaultCloseTimeout = 30 * time.Second
+)
+
+var (
+	errQueueFull = errors.New("the queue is full")
+	errClosed    = errors.New("the handler is closed")
+	errTimeout   = errors.New("can not send the queued records: timeout")
+)
+
+// permanentError marks errors which will not go away by retrying, for
+// example a rejected request.
+type permanentError struct {
+	error
+}
+
+// partialError marks a batch of which only some records failed. Only the
+// records of the error are retried.
+type partialError struct {
+	error
+	records []Record
+}
+
+// Batcher collects records in a bounded queue and passes them in batches
+// to the handler it is embedded in, for example the OTLPHandler. The
+// batches are sent in the background. A batch is sent as soon as it is
+// full and all queued records are sent every BatchAge, so a record waits
+// at most about BatchAge. Failed batches are retried with an exponential
+// backoff. Records which arrive while the queue is full are dropped and
+// counted. If Spool is true batches which still failed after all retries
+// are put back into the queue and sent again with the next batch instead
+// of being dropped.
+//
+// The fields must not be changed after the first record was handled.
+// Close should be called before the program exits so no records are lost.
+type Batcher struct {
+	BatchSize    int
+	BatchAge     time.Duration
+	QueueSize    int
+	Retries      int
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	CloseTimeout time.Duration
+	Spool        bool
+
+	send func(re []Record) error
+
+	once    sync.Once
+	mutex   sync.Mutex
+	queue   []Record
+	closed  bool
+	wake    chan struct{}
+	flush   chan chan error
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+func newBatcher(se func(re []Record) error) Batcher {
+	return Batcher{
+		BatchSize:    DefaultBatchSize,
+		BatchAge:     DefaultBatchAge,
+		QueueSize:    DefaultQueueSize,
+		Retries:      DefaultRetries,
+		Backoff:      DefaultBackoff,
+		MaxBackoff:   DefaultMaxBackoff,
+		CloseTimeout: DefaultCloseTimeout,
+		send:         se,
+	}
+}
+
+func (ba *Batcher) start() {
+	ba.once.Do(func() {
+		ba.wake = make(chan struct{}, 1)
+		ba.flush = make(chan chan error)
+		ba.done = make(chan struct{})
+
+		go ba.run()
+	})
+}
+
+// enqueue adds the record to the queue.
+func (ba *Batcher) enqueue(re Record) error {
+	ba.start()
+
+	ba.mutex.Lock()
+
+	if ba.closed {
+		ba.mutex.Unlock()
+		return errClosed
+	}
+
+	if len(ba.queue) >= ba.QueueSize {
+		ba.mutex.Unlock()
+		ba.dropped.Add(1)
+		return errQueueFull
+	}
+
+	ba.queue = append(ba.queue, re)
+	f := len(ba.queue) >= ba.BatchSize
+
+	ba.mutex.Unlock()
+
+	if f {
+		select {
+		case ba.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (ba *Batcher) run() {
+	t := time.NewTimer(ba.BatchAge)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ba.wake:
+			ba.sendBatches(false)
+		case <-t.C:
+			ba.sendBatches(true)
+			t.Reset(ba.BatchAge)
+		case c := <-ba.flush:
+			c <- ba.sendBatches(true)
+		case <-ba.done:
+			return
+		}
+	}
+}
+
+// sendBatches sends all full batches in the queue and the last partial
+// batch if all is true. It returns the first error. A batch which is
+// spooled stops the sending until the next attempt.
+func (ba *Batcher) sendBatches(all bool) (err error) {
+	for {
+		ba.mutex.Lock()
+
+		n := len(ba.queue)
+		if n == 0 || (!all && n < ba.BatchSize) {
+			ba.mutex.Unlock()
+			return
+		}
+
+		if n > ba.BatchSize {
+			n = ba.BatchSize
+		}
+
+		b := make([]Record, n)
+		copy(b, ba.queue)
+		ba.queue = append(ba.queue[:0], ba.queue[n:]...)
+
+		ba.mutex.Unlock()
+
+		b, e := ba.retry(b)
+		if e == nil {
+			continue
+		}
+
+		list.handleError(logger{Logger: b[0].Logger}, nil, e)
+
+		if err == nil {
+			err = e
+		}
+
+		var p permanentError
+		if ba.Spool && !errors.As(e, &p) {
+			ba.requeue(b)
+			return
+		}
+	}
+}
+
+// requeue puts the batch back at the front of the queue. Records of the
+// batch which do not fit into the queue anymore are dropped.
+func (ba *Batcher) requeue(re []Record) {
+	ba.mutex.Lock()
+	defer ba.mutex.Unlock()
+
+	n := ba.QueueSize - len(ba.queue)
+	if n < 0 {
+		n = 0
+	}
+
+	if n < len(re) {
+		ba.dropped.Add(uint64(len(re) - n))
+		re = re[:n]
+	}
+
+	q := make([]Record, 0, len(re)+len(ba.queue))
+	q = append(q, re...)
+	ba.queue = append(q, ba.queue...)
+}
+
+// retry sends the batch and retries it with an exponential backoff until
+// it succeeded, the error is permanent or all retries are used. It
+// returns the records which could not be sent.
+func (ba *Batcher) retry(re []Record) (rest []Record, err error) {
+	d := ba.Backoff
+	rest = re
+
+	for i := 0; ; i++ {
+		err = ba.send(rest)
+		if err == nil {
+			rest = nil
+			return
+		}
+
+		var a partialError
+		if errors.As(err, &a) {
+			rest = a.records
+		}
+
+		var p permanentError
+		if errors.As(err, &p) || i >= ba.Retries {
+			return
+		}
+
+		time.Sleep(d)
+
+		d *= 2
+		if d > ba.MaxBackoff {
+			d = ba.MaxBackoff
+		}
+	}
+}
+
+// Flush sends all queued records and returns the first error.
+func (ba *Batcher) Flush() error {
+	return ba.flushTimeout(nil)
+}
+
+// flushTimeout is Flush which gives up when the timeout channel fires.
+func (ba *Batcher) flushTimeout(ti <-chan time.Time) error {
+	ba.start()
+
+	// The channel is buffered so the sender does not block if the flush
+	// timed out.
+	c := make(chan error, 1)
+
+	select {
+	case ba.flush <- c:
+	case <-ba.done:
+		return errClosed
+	case <-ti:
+		return errTimeout
+	}
+
+	select {
+	case err := <-c:
+		return err
+	case <-ti:
+		return errTimeout
+	}
+}
+
+// Close sends all queued records and stops the handler. Records which
+// arrive after Close are rejected. If the records could not be sent
+// within CloseTimeout Close gives up and the remaining records are lost.
+func (ba *Batcher) Close() error {
+	ba.start()
+
+	ba.mutex.Lock()
+	if ba.closed {
+		ba.mutex.Unlock()
+		return errClosed
+	}
+	ba.closed = true
+	ba.mutex.Unlock()
+
+	var ti <-chan time.Time
+	if ba.CloseTimeout > 0 {
+		t := time.NewTimer(ba.CloseTimeout)
+		defer t.Stop()
+
+		ti = t.C
+	}
+
+	err := ba.flushTimeout(ti)
+	close(ba.done)
+
+	return err
+}
+
+// Dropped returns the number of records which were dropped because the
+// queue was full.
+func (ba *Batcher) Dropped() uint64 {
+	return ba.dropped.Load()
+}
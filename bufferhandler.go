@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"sync"
+)
+
+// Defaults of a BufferHandler returned by NewBufferHandler.
+const (
+	DefaultBufferKeys = 1024
+)
+
+// BufferHandler keeps the last records of every logger in a ring buffer
+// and only passes them to its target when a record with the trigger
+// priority or above arrives, like the fingers crossed handler of other
+// logging frameworks. This allows running at a high priority level but
+// still getting the debug messages which lead up to an error.
+//
+// The loggers using the handler need a priority level low enough to
+// create the records which should be buffered. Outputs with a higher
+// MinPriority can be used to print the other records directly.
+//
+// If Key is set the records are buffered per value of the field with
+// that key instead of per logger, for example per request id. Records
+// without the field are buffered per logger. At most MaxKeys buffers are
+// kept, the oldest buffer is dropped when a new one is needed.
+//
+// The fields must not be changed after the handler was added to a
+// logger.
+type BufferHandler struct {
+	Target  Handler
+	Size    int
+	Trigger Priority
+	Key     string
+	MaxKeys int
+
+	mutex   sync.Mutex
+	buffers map[bufferkey]*ring
+	keys    []bufferkey
+}
+
+type bufferkey struct {
+	logger Logger
+	field  string
+}
+
+type ring struct {
+	records []Record
+	next    int
+	full    bool
+}
+
+// NewBufferHandler returns a BufferHandler which keeps the last size
+// records per logger and passes them to the target when a record with
+// the trigger priority or above arrives.
+func NewBufferHandler(ta Handler, size int, trigger Priority) *BufferHandler {
+	return &BufferHandler{
+		Target:  ta,
+		Size:    size,
+		Trigger: trigger,
+		MaxKeys: DefaultBufferKeys,
+	}
+}
+
+// Enabled implements the Handler interface. Records of all priorities
+// are buffered.
+func (bu *BufferHandler) Enabled(lo Logger, pr Priority) bool {
+	return true
+}
+
+// Handle implements the Handler interface.
+func (bu *BufferHandler) Handle(re Record) error {
+	k := bu.key(&re)
+
+	bu.mutex.Lock()
+
+	if re.Priority < bu.Trigger {
+		bu.push(k, re)
+		bu.mutex.Unlock()
+
+		return nil
+	}
+
+	r := bu.take(k)
+	bu.mutex.Unlock()
+
+	var err error
+	for _, v := range append(r, re) {
+		if !bu.Target.Enabled(v.Logger, v.Priority) {
+			continue
+		}
+
+		e := bu.Target.Handle(v)
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+func (bu *BufferHandler) key(re *Record) bufferkey {
+	if bu.Key != "" {
+		for _, f := range re.Fields {
+			if f.Key == bu.Key {
+				return bufferkey{field: f.Value}
+			}
+		}
+	}
+
+	return bufferkey{logger: re.Logger}
+}
+
+// push adds the record to the buffer of the key. The mutex has to be
+// held by the caller.
+func (bu *BufferHandler) push(k bufferkey, re Record) {
+	if bu.Size <= 0 {
+		return
+	}
+
+	if bu.buffers == nil {
+		bu.buffers = make(map[bufferkey]*ring)
+	}
+
+	r, x := bu.buffers[k]
+	if !x {
+		if bu.MaxKeys > 0 && len(bu.keys) >= bu.MaxKeys {
+			delete(bu.buffers, bu.keys[0])
+			bu.keys = bu.keys[1:]
+		}
+
+		r = &ring{records: make([]Record, bu.Size)}
+		bu.buffers[k] = r
+		bu.keys = append(bu.keys, k)
+	}
+
+	r.records[r.next] = re
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// take removes the buffer of the key and returns its records in the
+// order they arrived. The mutex has to be held by the caller.
+func (bu *BufferHandler) take(k bufferkey) []Record {
+	r, x := bu.buffers[k]
+	if !x {
+		return nil
+	}
+
+	delete(bu.buffers, k)
+	for i, v := range bu.keys {
+		if v == k {
+			bu.keys = append(bu.keys[:i], bu.keys[i+1:]...)
+			break
+		}
+	}
+
+	if !r.full {
+		return r.records[:r.next]
+	}
+
+	return append(r.records[r.next:], r.records[:r.next]...)
+}
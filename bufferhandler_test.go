@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+	"testing"
+)
+
+func TestBufferHandler(t *testing.T) {
+	l := New(namet + ".BufferHandler")
+
+	n := New(namet + ".BufferHandler.Test")
+	n.SetLevel(Debug)
+	n.SetOutput(ioutil.Discard)
+
+	h := new(recordHandler)
+	n.AddHandler(NewBufferHandler(h, 3, Error))
+
+	for i := 0; i < 5; i++ {
+		n.Debug("Debug ", i)
+	}
+
+	if len(h.Records()) != 0 {
+		l.Critical("Records were passed before the trigger")
+		t.Fail()
+	}
+
+	n.Error("Error")
+
+	v := []string{"Debug 2", "Debug 3", "Debug 4", "Error"}
+
+	o := h.Records()
+	if len(o) != len(v) {
+		l.Critical("GOT: ", len(o), " records, EXPECTED: ", len(v))
+		t.FailNow()
+	}
+
+	for i := range v {
+		if o[i].Message != v[i] {
+			l.Critical("GOT: ", o[i].Message, ", EXPECTED: ", v[i])
+			t.Fail()
+		}
+	}
+
+	n.Debug("Debug")
+	n.Critical("Critical")
+
+	o = h.Records()
+	if len(o) != 6 || o[4].Message != "Debug" || o[5].Message != "Critical" {
+		l.Critical("Wrong records after the second trigger: ", o)
+		t.Fail()
+	}
+}
+
+func TestBufferHandlerKey(t *testing.T) {
+	l := New(namet + ".BufferHandler.Key")
+
+	n := New(namet + ".BufferHandler.Key.Test")
+	n.SetLevel(Debug)
+	n.SetFormat("{{.Message}}\n")
+	n.SetOutput(ioutil.Discard)
+
+	var b bytes.Buffer
+	w := NewWriterHandler(&b, TextEncoder{Format: "{{.Priority}} {{.Message}}{{.Fields}}\n"})
+	w.NoColor = true
+
+	u := NewBufferHandler(w, 10, Error)
+	u.Key = "request"
+	n.AddHandler(u)
+
+	r := 0
+	n.AddMiddleware(func(re *Record) {
+		re.Fields = []Field{{Key: "request", Value: strconv.Itoa(r)}}
+	})
+
+	for r = 0; r < 3; r++ {
+		n.Debug("Start")
+	}
+
+	r = 1
+	n.Error("Failed")
+
+	o := b.String()
+	v := "Debug Start request=1\nError Failed request=1\n"
+	if o != v {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+}
@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Collapser collapses identical records written to an output, like the
+// "last message repeated" lines of syslog. Records are identical if they
+// have the same logger, priority, message and fields. Repeats of a
+// record within Window of its first occurrence are not written, instead
+// a summary with the number of repeats and their time span is written
+// when a different record arrives or the window closes.
+//
+// A Collapser belongs to a single output. Window must not be changed
+// after the output was added.
+type Collapser struct {
+	Window time.Duration
+
+	mutex  sync.Mutex
+	last   Record
+	set    bool
+	first  time.Time
+	seen   time.Time
+	count  uint64
+	logger logger
+	output Output
+	timer  *time.Timer
+}
+
+// NewCollapser returns a Collapser with the given window.
+func NewCollapser(wi time.Duration) *Collapser {
+	return &Collapser{
+		Window: wi,
+	}
+}
+
+// pass returns true if the record has to be written to the output. It
+// writes the summary of the collapsed records before a different record
+// is written.
+func (co *Collapser) pass(lo logger, ou *Output, re *Record) bool {
+	co.mutex.Lock()
+
+	if co.set && sameRecord(&co.last, re) && re.Time.Sub(co.first) <= co.Window {
+		co.count++
+		co.seen = re.Time
+		co.logger = lo
+		co.output = *ou
+
+		if co.timer == nil {
+			co.timer = time.AfterFunc(co.first.Add(co.Window).Sub(time.Now()), co.close)
+		}
+
+		co.mutex.Unlock()
+		return false
+	}
+
+	l, o, s := co.summary()
+
+	co.last = *re
+	co.set = true
+	co.first = re.Time
+	co.seen = re.Time
+
+	co.mutex.Unlock()
+
+	writeSummary(l, o, s)
+
+	return true
+}
+
+// close is called when the window closes. It writes the summary and
+// forgets the last record so the next record is written again.
+func (co *Collapser) close() {
+	co.mutex.Lock()
+
+	l, o, s := co.summary()
+	co.set = false
+
+	co.mutex.Unlock()
+
+	writeSummary(l, o, s)
+}
+
+// summary returns the encoded summary of the collapsed records together
+// with the logger and output it has to be written to. The line is nil if
+// there are no collapsed records. The mutex has to be held by the caller.
+func (co *Collapser) summary() (lo logger, ou Output, li []byte) {
+	if co.timer != nil {
+		co.timer.Stop()
+		co.timer = nil
+	}
+
+	if co.count == 0 {
+		return
+	}
+
+	r := Record{
+		Time:     co.seen,
+		Logger:   co.last.Logger,
+		Priority: co.last.Priority,
+		Message:  "last message repeated " + strconv.FormatUint(co.count, 10) + " times in " + co.seen.Sub(co.first).String(),
+	}
+	co.count = 0
+
+	lo = co.logger
+	ou = co.output
+	li = ou.encode(nil, &r, lo)
+
+	return
+}
+
+// writeSummary writes the summary line to the output. It must be called
+// without holding the mutex because the error handler may log to the
+// same output again.
+func writeSummary(lo logger, ou Output, li []byte) {
+	if li == nil {
+		return
+	}
+
+	err := writeOutput(ou.writerState(), ou.Writer, li)
+	if err != nil {
+		list.handleError(lo, li, err)
+	}
+}
+
+func sameRecord(a, b *Record) bool {
+	if a.Logger != b.Logger || a.Priority != b.Priority || a.Message != b.Message {
+		return false
+	}
+
+	if len(a.Fields) != len(b.Fields) {
+		return false
+	}
+
+	for i := range a.Fields {
+		if a.Fields[i] != b.Fields[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (sy *syncBuffer) Write(p []byte) (int, error) {
+	sy.mutex.Lock()
+	defer sy.mutex.Unlock()
+
+	return sy.buffer.Write(p)
+}
+
+func (sy *syncBuffer) String() string {
+	sy.mutex.Lock()
+	defer sy.mutex.Unlock()
+
+	return sy.buffer.String()
+}
+
+func TestCollapser(t *testing.T) {
+	l := New(namet + ".Collapser")
+
+	n := New(namet + ".Collapser.Test")
+	n.SetFormat("{{.Message}}\n")
+
+	var b syncBuffer
+	o := NewOutput(&b)
+	o.Collapser = NewCollapser(100 * time.Millisecond)
+
+	n.SetOutput(&b)
+	n.RemoveOutput(&b)
+	n.AddOutput(o)
+
+	for i := 0; i < 5; i++ {
+		n.Notice("First")
+	}
+	n.Warning("First")
+	n.Notice("Second")
+	n.Notice("Second")
+	n.Notice("Second")
+
+	v := []string{
+		"First",
+		"last message repeated 4 times in ",
+		"First",
+		"Second",
+	}
+
+	c := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(c) != len(v) {
+		l.Critical("GOT: ", c, ", EXPECTED: ", v)
+		t.FailNow()
+	}
+
+	for i := range v {
+		if !strings.HasPrefix(c[i], v[i]) {
+			l.Critical("GOT: ", c[i], ", EXPECTED: ", v[i])
+			t.Fail()
+		}
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	c = strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(c) != 5 || !strings.HasPrefix(c[4], "last message repeated 2 times in ") {
+		l.Critical("GOT: ", c, ", EXPECTED a summary after the window closed")
+		t.FailNow()
+	}
+
+	n.Notice("Second")
+
+	c = strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(c) != 6 || c[5] != "Second" {
+		l.Critical("GOT: ", c, ", EXPECTED the message after the window closed")
+		t.Fail()
+	}
+}
+
+func TestCollapserErrorHandler(t *testing.T) {
+	l := New(namet + ".CollapserErrorHandler")
+
+	n := New(namet + ".CollapserErrorHandler.Test")
+
+	o := NewOutput(new(failWriter))
+	o.Collapser = NewCollapser(time.Hour)
+
+	n.SetOutput(o.Writer)
+	n.RemoveOutput(o.Writer)
+	n.AddOutput(o)
+
+	// Only the error of the summary is logged, otherwise the logged
+	// errors would end the collapsing.
+	var e atomic.Bool
+	SetErrorHandler(func(lo Logger, err error) {
+		if lo == n && e.Load() {
+			n.Warning("Can not write message: ", err)
+		}
+	})
+	defer SetErrorHandler(nil)
+
+	d := make(chan struct{})
+	go func() {
+		n.Notice("First")
+		n.Notice("First")
+		n.Notice("First")
+		e.Store(true)
+		n.Notice("Second")
+		close(d)
+	}()
+
+	select {
+	case <-d:
+	case <-time.After(5 * time.Second):
+		l.Critical("Writing the summary deadlocked")
+		t.FailNow()
+	}
+}
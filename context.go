@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"context"
+)
+
+// Keys of the fields added by the context helpers.
+const (
+	FieldRequestID = "request_id"
+	FieldTraceID   = "trace_id"
+	FieldUser      = "user"
+)
+
+type contextkey int
+
+const (
+	contextlogger contextkey = iota
+	contextfields
+	contextlevel
+	contexttrace
+)
+
+// NewContext returns a copy of the context which carries the logger.
+func NewContext(ctx context.Context, lo Logger) context.Context {
+	return context.WithValue(ctx, contextlogger, lo)
+}
+
+// FromContext returns the logger carried by the context. If the context
+// carries no logger the root logger is returned.
+func FromContext(ctx context.Context) Logger {
+	l, ok := ctx.Value(contextlogger).(Logger)
+	if !ok {
+		return defroot
+	}
+
+	return l
+}
+
+// WithFields returns a copy of the context which carries the fields in
+// addition to the fields already carried by the context. The fields are
+// added to every message logged with the context.
+func WithFields(ctx context.Context, fi ...Field) context.Context {
+	o := ContextFields(ctx)
+
+	// The slice is created with the exact size so appending to the fields
+	// of a record never changes the fields of the context.
+	f := make([]Field, len(o)+len(fi))
+	copy(f, o)
+	copy(f[len(o):], fi)
+
+	return context.WithValue(ctx, contextfields, f)
+}
+
+// WithRequestID returns a copy of the context which carries the request
+// id as a field.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return WithFields(ctx, Field{Key: FieldRequestID, Value: id})
+}
+
+// WithTraceID returns a copy of the context which carries the trace id as
+// a field.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return WithFields(ctx, Field{Key: FieldTraceID, Value: id})
+}
+
+// WithUser returns a copy of the context which carries the user as a
+// field.
+func WithUser(ctx context.Context, us string) context.Context {
+	return WithFields(ctx, Field{Key: FieldUser, Value: us})
+}
+
+// ContextFields returns the fields carried by the context. The returned
+// slice must not be modified.
+func ContextFields(ctx context.Context) []Field {
+	f, _ := ctx.Value(contextfields).([]Field)
+
+	return f
+}
+
+// logMessageCtx logs the message with the fields of the context and the
+// given fields.
+func logMessageCtx(ctx context.Context, lo Logger, pr Priority, fi []Field, me ...interface{}) {
+	l := list.GetLogger(lo)
+
+	if list.level(ctx, l) > pr {
+		return
+	}
+
+	if l.RateLimiter != nil && !l.RateLimiter.allow(lo, pr, 3) {
+		return
+	}
+
+	f := ContextFields(ctx)
+	if len(fi) != 0 {
+		c := make([]Field, len(f)+len(fi))
+		copy(c, f)
+		copy(c[len(f):], fi)
+		f = c
+	}
+
+	t, _ := TraceFromContext(ctx)
+
+	printFields(l, pr, t, f, me...)
+}
+
+// LogCtx logs a message with the given priority. The fields and the
+// trace context carried by the context are added to the message and a
+// level carried by the context is used, see WithLevel.
+func (lo Logger) LogCtx(ctx context.Context, pr Priority, me ...interface{}) {
+	logMessageCtx(ctx, lo, pr, nil, me...)
+}
+
+// TraceCtx logs a message with the Trace priority. See LogCtx.
+func (lo Logger) TraceCtx(ctx context.Context, me ...interface{}) {
+	logMessageCtx(ctx, lo, Trace, nil, me...)
+}
+
+// DebugCtx logs a message with the Debug priority. See LogCtx.
+func (lo Logger) DebugCtx(ctx context.Context, me ...interface{}) {
+	logMessageCtx(ctx, lo, Debug, nil, me...)
+}
+
+// InfoCtx logs a message with the Info priority. See LogCtx.
+func (lo Logger) InfoCtx(ctx context.Context, me ...interface{}) {
+	logMessageCtx(ctx, lo, Info, nil, me...)
+}
+
+// NoticeCtx logs a message with the Notice priority. See LogCtx.
+func (lo Logger) NoticeCtx(ctx context.Context, me ...interface{}) {
+	logMessageCtx(ctx, lo, Notice, nil, me...)
+}
+
+// WarningCtx logs a message with the Warning priority. See LogCtx.
+func (lo Logger) WarningCtx(ctx context.Context, me ...interface{}) {
+	logMessageCtx(ctx, lo, Warning, nil, me...)
+}
+
+// ErrorCtx logs a message with the Error priority. See LogCtx.
+func (lo Logger) ErrorCtx(ctx context.Context, me ...interface{}) {
+	logMessageCtx(ctx, lo, Error, nil, me...)
+}
+
+// CriticalCtx logs a message with the Critical priority. See LogCtx.
+func (lo Logger) CriticalCtx(ctx context.Context, me ...interface{}) {
+	logMessageCtx(ctx, lo, Critical, nil, me...)
+}
+
+// AlertCtx logs a message with the Alert priority. See LogCtx.
+func (lo Logger) AlertCtx(ctx context.Context, me ...interface{}) {
+	logMessageCtx(ctx, lo, Alert, nil, me...)
+}
+
+// EmergencyCtx logs a message with the Emergency priority. See LogCtx.
+func (lo Logger) EmergencyCtx(ctx context.Context, me ...interface{}) {
+	logMessageCtx(ctx, lo, Emergency, nil, me...)
+}
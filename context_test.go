@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	l := New(namet + ".Context")
+
+	n := New(namet + ".Context.Test")
+	n.SetFormat("{{.Message}}{{.Fields}}\n")
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+
+	c := context.Background()
+	if FromContext(c) != defroot {
+		l.Critical("Empty context should return the root logger")
+		t.Fail()
+	}
+
+	c = NewContext(c, n)
+	c = WithRequestID(c, "1")
+	c = WithTraceID(c, "2")
+	c = WithUser(c, "test")
+
+	d := WithFields(c, Field{Key: "key", Value: "value"})
+
+	n.AddMiddleware(AddField("host", "test"))
+
+	FromContext(c).NoticeCtx(c, "Test")
+	FromContext(d).WarningCtx(d, "Test ", 1)
+	n.DebugCtx(d, "Hidden")
+
+	v := "Test request_id=1 trace_id=2 user=test host=test\n" +
+		"Test 1 request_id=1 trace_id=2 user=test key=value host=test\n"
+
+	o := b.String()
+	if o != v {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+
+	if len(ContextFields(c)) != 3 {
+		l.Critical("Middleware changed the fields of the context")
+		t.Fail()
+	}
+}
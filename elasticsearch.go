@@ -0,0 +1,220 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the ElasticsearchHandler.
+const (
+	DefaultElasticsearchURL         = "http://localhost:9200"
+	DefaultElasticsearchIndex       = "logs"
+	DefaultElasticsearchIndexFormat = "2006.01.02"
+)
+
+// ecsfields maps the well known fields to fields of the Elastic Common
+// Schema.
+var ecsfields = map[string]string{
+	FieldStackTrace: "error.stack_trace",
+	FieldRequestID:  "http.request.id",
+	FieldTraceID:    "trace.id",
+	FieldUser:       "user.name",
+	FieldMethod:     "http.request.method",
+	FieldPath:       "url.path",
+	FieldStatus:     "http.response.status_code",
+	FieldBytes:      "http.response.body.bytes",
+	FieldRemoteAddr: "client.address",
+	FieldUserAgent:  "user_agent.original",
+}
+
+// ElasticsearchHandler is a Handler which indexes records in
+// Elasticsearch with the bulk API. Records are mapped to the Elastic
+// Common Schema with the fields "@timestamp", "log.level", "log.logger",
+// "message" and "trace.id" and "span.id" if the record has a trace
+// context. Well known fields like FieldStackTrace are mapped to their ECS
+// fields, for example "error.stack_trace", all other fields are added as
+// labels. Every record is written to the index named Index followed by a
+// dash and its date formatted with IndexFormat. An empty IndexFormat
+// writes all records to Index.
+//
+// Records are batched in the background, see Batcher. Items which
+// Elasticsearch rejected because it is overloaded are retried, all other
+// rejected items are passed to the error handler. Header is sent with
+// every request, for example for authentication.
+type ElasticsearchHandler struct {
+	Batcher
+
+	URL         string
+	Index       string
+	IndexFormat string
+	Client      *http.Client
+	Header      http.Header
+	MinPriority Priority
+}
+
+// NewElasticsearchHandler returns an ElasticsearchHandler which indexes
+// records in the Elasticsearch cluster with the given URL. An empty URL
+// uses DefaultElasticsearchURL. Requests time out after
+// DefaultHTTPTimeout.
+func NewElasticsearchHandler(url string) *ElasticsearchHandler {
+	if url == "" {
+		url = DefaultElasticsearchURL
+	}
+
+	h := &ElasticsearchHandler{
+		URL:         strings.TrimSuffix(url, "/"),
+		Index:       DefaultElasticsearchIndex,
+		IndexFormat: DefaultElasticsearchIndexFormat,
+		Client:      &http.Client{Timeout: DefaultHTTPTimeout},
+		Header:      make(http.Header),
+	}
+	h.Batcher = newBatcher(h.send)
+
+	return h
+}
+
+// Enabled implements the Handler interface.
+func (es *ElasticsearchHandler) Enabled(lo Logger, pr Priority) bool {
+	return pr >= es.MinPriority
+}
+
+// Handle implements the Handler interface. The record is queued and sent
+// in the background.
+func (es *ElasticsearchHandler) Handle(re Record) error {
+	return es.enqueue(re)
+}
+
+func (es *ElasticsearchHandler) index(ti time.Time) string {
+	if es.IndexFormat == "" {
+		return es.Index
+	}
+
+	return es.Index + "-" + ti.UTC().Format(es.IndexFormat)
+}
+
+// ecsFields returns the ECS fields of the record. Later fields replace
+// earlier fields with the same key.
+func ecsFields(re *Record) []Field {
+	f := []Field{
+		{"@timestamp", re.Time.UTC().Format(time.RFC3339Nano)},
+		{"log.level", strings.ToLower(priorities[re.Priority])},
+		{"log.logger", string(re.Logger)},
+		{"message", re.Message},
+	}
+
+	if re.TraceID != "" {
+		f = append(f, Field{"trace.id", re.TraceID}, Field{"span.id", re.SpanID})
+	}
+
+	for _, v := range re.Fields {
+		k, x := ecsfields[v.Key]
+		if !x {
+			k = "labels." + strings.Replace(v.Key, ".", "_", -1)
+		}
+
+		if traceField(re, v) {
+			continue
+		}
+
+		d := false
+		for i := range f {
+			if f[i].Key == k {
+				f[i].Value = v.Value
+				d = true
+				break
+			}
+		}
+
+		if !d {
+			f = append(f, Field{k, v.Value})
+		}
+	}
+
+	return f
+}
+
+// appendBulk appends the create action and the document of the record.
+func (es *ElasticsearchHandler) appendBulk(b []byte, re *Record) []byte {
+	b = append(b, `{"create":{"_index":`...)
+	b = appendJSONString(b, es.index(re.Time))
+	b = append(b, "}}\n{"...)
+
+	for i, f := range ecsFields(re) {
+		if i > 0 {
+			b = append(b, ',')
+		}
+
+		b = appendJSONString(b, f.Key)
+		b = append(b, ':')
+		b = appendJSONString(b, f.Value)
+	}
+
+	return append(b, "}\n"...)
+}
+
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkResponseItem `json:"items"`
+}
+
+type bulkResponseItem struct {
+	Status int `json:"status"`
+	Error  struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+func (es *ElasticsearchHandler) send(re []Record) error {
+	var b []byte
+	for i := range re {
+		b = es.appendBulk(b, &re[i])
+	}
+
+	o, err := postRequest(es.Client, es.URL+"/_bulk", es.Header, "application/x-ndjson", b)
+	if err != nil {
+		return err
+	}
+
+	var r bulkResponse
+	err = json.Unmarshal(o, &r)
+	if err != nil {
+		return permanentError{errors.New("can not parse bulk response: " + err.Error())}
+	}
+
+	if !r.Errors {
+		return nil
+	}
+
+	if len(r.Items) != len(re) {
+		return permanentError{errors.New("can not parse bulk response: expected " + strconv.Itoa(len(re)) + " items, got " + strconv.Itoa(len(r.Items)))}
+	}
+
+	var f []Record
+
+	for i, m := range r.Items {
+		for _, v := range m {
+			if v.Status >= 200 && v.Status < 300 {
+				continue
+			}
+
+			if v.Status >= 500 || v.Status == http.StatusTooManyRequests {
+				f = append(f, re[i])
+				continue
+			}
+
+			e := errors.New("can not index record: " + v.Error.Type + ": " + v.Error.Reason)
+			list.handleError(logger{Logger: re[i].Logger}, nil, e)
+		}
+	}
+
+	if len(f) == 0 {
+		return nil
+	}
+
+	return partialError{errors.New("can not index " + strconv.Itoa(len(f)) + " records: rejected by the server"), f}
+}
@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// readBulk returns the actions and documents of a bulk request.
+func readBulk(r *http.Request) (ac []map[string]map[string]string, do []map[string]string, err error) {
+	s := bufio.NewScanner(r.Body)
+	s.Buffer(nil, 1<<20)
+
+	for i := 0; s.Scan(); i++ {
+		if i%2 == 0 {
+			var a map[string]map[string]string
+			err = json.Unmarshal(s.Bytes(), &a)
+			ac = append(ac, a)
+		} else {
+			var d map[string]string
+			err = json.Unmarshal(s.Bytes(), &d)
+			do = append(do, d)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func TestElasticsearchHandler(t *testing.T) {
+	l := New(namet + ".ElasticsearchHandler")
+
+	var mu sync.Mutex
+	var a []map[string]map[string]string
+	var d []map[string]string
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			http.Error(w, "wrong request", http.StatusBadRequest)
+			return
+		}
+
+		o, e, err := readBulk(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		a = append(a, o...)
+		d = append(d, e...)
+		mu.Unlock()
+
+		w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}))
+	defer s.Close()
+
+	h := NewElasticsearchHandler(s.URL + "/")
+
+	n := New(namet + ".ElasticsearchHandler.Test")
+	n.SetLevel(Info)
+	n.SetOutput(ioutil.Discard)
+	n.AddMiddleware(AddStackTrace(Error))
+	n.AddMiddleware(AddField("user", "alice"))
+	n.AddMiddleware(AddField("custom.key", "value"))
+	n.AddHandler(h)
+
+	n.Warning("Test1")
+	n.Error("Test2")
+
+	err := h.Close()
+	if err != nil {
+		l.Critical("Can not close handler: ", err)
+		t.FailNow()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(a) != 2 || len(d) != 2 {
+		l.Critical("GOT: ", len(a), " actions and ", len(d), " documents, EXPECTED: 2 and 2")
+		t.FailNow()
+	}
+
+	i := "logs-" + time.Now().UTC().Format("2006.01.02")
+	if a[0]["create"]["_index"] != i {
+		l.Critical("GOT: ", a[0], ", EXPECTED: ", i)
+		t.Fail()
+	}
+
+	v := map[string]string{
+		"log.level":         "warning",
+		"log.logger":        string(n),
+		"message":           "Test1",
+		"user.name":         "alice",
+		"labels.custom_key": "value",
+	}
+
+	for k, e := range v {
+		if d[0][k] != e {
+			l.Critical("GOT: ", d[0][k], ", EXPECTED: ", e, " for ", k)
+			t.Fail()
+		}
+	}
+
+	if _, err := time.Parse(time.RFC3339Nano, d[0]["@timestamp"]); err != nil {
+		l.Critical("Wrong timestamp: ", d[0]["@timestamp"])
+		t.Fail()
+	}
+
+	if _, x := d[0]["error.stack_trace"]; x {
+		l.Critical("Unexpected stack trace: ", d[0])
+		t.Fail()
+	}
+
+	if !strings.Contains(d[1]["error.stack_trace"], "goroutine") {
+		l.Critical("Missing stack trace: ", d[1])
+		t.Fail()
+	}
+}
+
+func TestElasticsearchHandlerPartial(t *testing.T) {
+	l := New(namet + ".ElasticsearchHandlerPartial")
+
+	var mu sync.Mutex
+	var m [][]string
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, e, err := readBulk(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var o []string
+		for _, v := range e {
+			o = append(o, v["message"])
+		}
+
+		mu.Lock()
+		m = append(m, o)
+		mu.Unlock()
+
+		var b bytes.Buffer
+		b.WriteString(`{"took":1,"errors":true,"items":[`)
+
+		for i, v := range o {
+			if i > 0 {
+				b.WriteString(",")
+			}
+
+			switch v {
+			case "Retry":
+				if len(o) > 1 {
+					b.WriteString(`{"create":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}}`)
+					continue
+				}
+			case "Invalid":
+				b.WriteString(`{"create":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`)
+				continue
+			}
+
+			b.WriteString(`{"create":{"status":201}}`)
+		}
+
+		b.WriteString("]}")
+		w.Write(b.Bytes())
+	}))
+	defer s.Close()
+
+	var e int32
+	SetErrorHandler(func(lo Logger, err error) {
+		if strings.Contains(err.Error(), "mapper_parsing_exception") {
+			atomic.AddInt32(&e, 1)
+		}
+	})
+	defer SetErrorHandler(nil)
+
+	h := NewElasticsearchHandler(s.URL)
+	h.Backoff = time.Millisecond
+
+	for _, v := range []string{"Test", "Retry", "Invalid"} {
+		h.Handle(Record{Time: time.Now(), Logger: namet + ".ElasticsearchHandlerPartial.Test", Priority: Info, Message: v})
+	}
+
+	err := h.Close()
+	if err != nil {
+		l.Critical("Can not close handler: ", err)
+		t.Fail()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(m) != 2 || len(m[0]) != 3 || len(m[1]) != 1 || m[1][0] != "Retry" {
+		l.Critical("GOT: ", m, ", EXPECTED: [[Test Retry Invalid] [Retry]]")
+		t.Fail()
+	}
+
+	if atomic.LoadInt32(&e) != 1 {
+		l.Critical("GOT: ", atomic.LoadInt32(&e), " errors, EXPECTED: 1")
+		t.Fail()
+	}
+}
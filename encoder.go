@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// Encoder turns a record into the bytes which are written to an output.
+// Encode appends the encoded record to the byte slice and returns the
+// result. The nc flag is true if colors are disabled for the output.
+type Encoder interface {
+	Encode(b []byte, re *Record, nc bool) []byte
+}
+
+// TextEncoder formats messages with the given Format. Colors are used
+// unless they are disabled for the logger or the output. An empty
+// TimeFormat uses RFC3339. If Sanitize is true control characters in
+// messages and fields are escaped.
+type TextEncoder struct {
+	Format     Format
+	TimeFormat string
+	Sanitize   bool
+}
+
+// Encode implements the Encoder interface.
+func (en TextEncoder) Encode(b []byte, re *Record, nc bool) []byte {
+	t := en.TimeFormat
+	if t == "" {
+		t = timeformat
+	}
+
+	return appendMessage(b, re, en.Format, t, nc, en.Sanitize)
+}
+
+// JSONEncoder formats every message as a JSON object on its own line
+// with the fields "time", "priority", "logger" and "message", the fields
+// "trace_id" and "span_id" if the record has a trace context, followed by
+// the fields of the record. A "trace_id" field is left out if the record
+// has a trace context. An empty TimeFormat uses RFC3339Nano. Colors are
+// never used.
+type JSONEncoder struct {
+	TimeFormat string
+}
+
+// Encode implements the Encoder interface.
+func (en JSONEncoder) Encode(b []byte, re *Record, nc bool) []byte {
+	t := en.TimeFormat
+	if t == "" {
+		t = time.RFC3339Nano
+	}
+
+	b = append(b, `{"time":"`...)
+	b = re.Time.AppendFormat(b, t)
+	b = append(b, `","priority":`...)
+	b = appendJSONString(b, priorities[re.Priority])
+	b = append(b, `,"logger":`...)
+	b = appendJSONString(b, string(re.Logger))
+	b = append(b, `,"message":`...)
+	b = appendJSONString(b, re.Message)
+
+	if re.TraceID != "" {
+		b = append(b, `,"trace_id":`...)
+		b = appendJSONString(b, re.TraceID)
+		b = append(b, `,"span_id":`...)
+		b = appendJSONString(b, re.SpanID)
+	}
+
+	for _, f := range re.Fields {
+		if traceField(re, f) {
+			continue
+		}
+
+		b = append(b, ',')
+		b = appendJSONString(b, f.Key)
+		b = append(b, ':')
+		b = appendJSONString(b, f.Value)
+	}
+
+	b = append(b, "}\n"...)
+
+	return b
+}
+
+const hexdigits = "0123456789abcdef"
+
+// appendJSONString appends the string quoted and escaped as a JSON
+// string. Invalid UTF-8 is replaced by the replacement character.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				b = append(b, '\\', c)
+			case c == '\n':
+				b = append(b, '\\', 'n')
+			case c == '\r':
+				b = append(b, '\\', 'r')
+			case c == '\t':
+				b = append(b, '\\', 't')
+			case c < 0x20 || c == 0x7f:
+				b = append(b, '\\', 'u', '0', '0', hexdigits[c>>4], hexdigits[c&0xf])
+			default:
+				b = append(b, c)
+			}
+
+			i++
+			continue
+		}
+
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && n == 1 {
+			b = append(b, "\ufffd"...)
+		} else {
+			b = append(b, s[i:i+n]...)
+		}
+
+		i += n
+	}
+
+	return append(b, '"')
+}
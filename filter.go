@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Filter decides if a record will be printed. Records for which a filter
+// returns false are dropped.
+type Filter func(re Record) bool
+
+// DropMessage returns a Filter which drops all records with a message
+// matching the regular expression.
+func DropMessage(ex *regexp.Regexp) Filter {
+	return func(re Record) bool {
+		return !ex.MatchString(re.Message)
+	}
+}
+
+// DropField returns a Filter which drops all records which have a field
+// with the given key and a value matching the regular expression.
+func DropField(key string, ex *regexp.Regexp) Filter {
+	return func(re Record) bool {
+		for _, f := range re.Fields {
+			if f.Key == key && ex.MatchString(f.Value) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// ParseFilter parses a filter from the given string. The string has the
+// form "message:<regexp>" to drop records with a matching message or
+// "field.<key>:<regexp>" to drop records with a matching field.
+func ParseFilter(fi string) (Filter, error) {
+	i := strings.Index(fi, ":")
+	if i < 0 {
+		return nil, errors.New("can not parse filter: missing ':' in " + fi)
+	}
+
+	t := fi[:i]
+
+	ex, err := regexp.Compile(fi[i+1:])
+	if err != nil {
+		return nil, errors.New("can not parse filter: " + err.Error())
+	}
+
+	if t == "message" {
+		return DropMessage(ex), nil
+	}
+
+	if strings.HasPrefix(t, "field.") && len(t) > len("field.") {
+		return DropField(t[len("field."):], ex), nil
+	}
+
+	return nil, errors.New("can not parse filter: unknown target " + t)
+}
+
+func checkFilters(fi []Filter, re Record) bool {
+	for _, f := range fi {
+		if !f(re) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,8 +1,9 @@
 package logger
 
 import (
-	"fmt"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -17,45 +18,173 @@ const (
 	textblink  = 5
 )
 
-type message struct {
-	Logger
-	Message  string
-	Priority string
-	Time     string
-}
+// Fields which can be used in a Format.
+const (
+	fieldtime     = "{{.Time}}"
+	fieldlogger   = "{{.Logger}}"
+	fieldpriority = "{{.Priority}}"
+	fieldmessage  = "{{.Message}}"
+	fieldfields   = "{{.Fields}}"
+	fieldtraceid  = "{{.TraceID}}"
+	fieldspanid   = "{{.SpanID}}"
+)
 
-func formatPriority(pr Priority, nc bool) string {
+func appendPriority(b []byte, pr Priority, nc bool) []byte {
 	c, f := getPriorityFormat(pr)
 
-	r := formatText(f, nc)
-	l := formatText(c, nc)
-	p := priorities[pr]
+	b = appendText(b, f, nc)
+	b = appendText(b, c, nc)
+	b = append(b, priorities[pr]...)
+	b = appendReset(b, nc)
 
-	s := r + l + p + formatReset(nc)
+	return b
+}
 
-	return s
+func appendReset(b []byte, nc bool) []byte {
+	return appendText(b, textnormal, nc)
 }
 
-func formatReset(nc bool) string {
-	s := formatText(textnormal, nc)
+func appendText(b []byte, fo int, nc bool) []byte {
+	if nc {
+		return b
+	}
+
+	b = append(b, "\033["...)
+	b = strconv.AppendInt(b, int64(fo), 10)
+	b = append(b, 'm')
 
-	return s
+	return b
 }
 
-func formatText(fo int, nc bool) string {
-	if nc {
-		return ""
+// appendMessage appends the message formatted with the given format,
+// time format and color setting to the byte slice. If sa is true control
+// characters in the logger name, the message and the fields are escaped.
+// The format is scanned once without building any intermediate strings.
+func appendMessage(b []byte, re *Record, fo Format, tf string, nc, sa bool) []byte {
+	s := string(fo)
+
+	for {
+		i := strings.Index(s, "{{.")
+		if i < 0 {
+			break
+		}
+
+		b = append(b, s[:i]...)
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, fieldtime):
+			b = re.Time.AppendFormat(b, tf)
+			s = s[len(fieldtime):]
+		case strings.HasPrefix(s, fieldlogger):
+			b = appendValue(b, string(re.Logger), sa)
+			s = s[len(fieldlogger):]
+		case strings.HasPrefix(s, fieldpriority):
+			b = appendPriority(b, re.Priority, nc)
+			s = s[len(fieldpriority):]
+		case strings.HasPrefix(s, fieldmessage):
+			b = appendValue(b, re.Message, sa)
+			s = s[len(fieldmessage):]
+		case strings.HasPrefix(s, fieldfields):
+			b = appendFields(b, re.Fields, sa)
+			s = s[len(fieldfields):]
+		case strings.HasPrefix(s, fieldtraceid):
+			b = appendValue(b, re.TraceID, sa)
+			s = s[len(fieldtraceid):]
+		case strings.HasPrefix(s, fieldspanid):
+			b = appendValue(b, re.SpanID, sa)
+			s = s[len(fieldspanid):]
+		default:
+			b = append(b, s[:3]...)
+			s = s[3:]
+		}
+	}
+
+	return append(b, s...)
+}
+
+// appendFields appends the fields as " key=value" pairs. Values which
+// are empty or contain spaces, quotes or control characters are quoted.
+func appendFields(b []byte, fi []Field, sa bool) []byte {
+	for _, f := range fi {
+		b = append(b, ' ')
+		b = appendValue(b, f.Key, sa)
+		b = append(b, '=')
+
+		if needsQuote(f.Value) {
+			b = strconv.AppendQuote(b, f.Value)
+		} else {
+			b = appendValue(b, f.Value, sa)
+		}
 	}
 
-	s := fmt.Sprintf("\033[%dm", fo)
-	return s
+	return b
 }
 
-func formatMessage(me *message, fo Format) (so string) {
-	so = strings.Replace(string(fo), "{{.Time}}", me.Time, -1)
-	so = strings.Replace(so, "{{.Logger}}", string(me.Logger), -1)
-	so = strings.Replace(so, "{{.Priority}}", me.Priority, -1)
-	so = strings.Replace(so, "{{.Message}}", me.Message, -1)
+func appendValue(b []byte, s string, sa bool) []byte {
+	if !sa {
+		return append(b, s...)
+	}
+
+	return appendSanitized(b, s)
+}
+
+// appendSanitized appends the string with all control characters
+// escaped so the string can not start new lines or send escape sequences
+// to terminals. Newlines, carriage returns and tabs are escaped as \n,
+// \r and \t, other control characters and the unicode line and paragraph
+// separators as \x or \u sequences.
+func appendSanitized(b []byte, s string) []byte {
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '\n':
+				b = append(b, '\\', 'n')
+			case c == '\r':
+				b = append(b, '\\', 'r')
+			case c == '\t':
+				b = append(b, '\\', 't')
+			case c < 0x20 || c == 0x7f:
+				b = append(b, '\\', 'x', hexdigits[c>>4], hexdigits[c&0xf])
+			default:
+				b = append(b, c)
+			}
+
+			i++
+			continue
+		}
+
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if (r >= 0x80 && r < 0xa0) || r == '\u2028' || r == '\u2029' {
+			b = append(b, '\\', 'u')
+			b = append(b, hexdigits[r>>12&0xf], hexdigits[r>>8&0xf], hexdigits[r>>4&0xf], hexdigits[r&0xf])
+		} else {
+			b = append(b, s[i:i+n]...)
+		}
+
+		i += n
+	}
+
+	return b
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c == '"' || c == '=' || c == 0x7f {
+			return true
+		}
+	}
+
+	return false
+}
 
-	return
+func formatMessage(re *Record, fo Format, tf string, nc, sa bool) string {
+	return string(appendMessage(nil, re, fo, tf, nc, sa))
 }
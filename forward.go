@@ -0,0 +1,265 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Defaults of the ForwardHandler.
+const (
+	DefaultForwardAddress = "localhost:24224"
+	DefaultForwardTimeout = 10 * time.Second
+)
+
+// ForwardHandler is a Handler which sends records to fluentd or fluent-bit
+// with the Fluent Forward protocol. Records are batched in the background,
+// see Batcher, and every batch is sent as one PackedForward message per
+// logger with the name of the logger as the tag. Every record has the
+// keys "priority", "logger" and "message", the keys "trace_id" and
+// "span_id" if it has a trace context and the fields of the record.
+//
+// If RequireAck is true every message carries a chunk id and the handler
+// waits until the server acknowledged it. Failed messages are sent again
+// on a new connection. While the server can not be reached the records are
+// spooled in the queue. Records can therefore be delivered more than once.
+type ForwardHandler struct {
+	Batcher
+
+	Network     string
+	Address     string
+	RequireAck  bool
+	Timeout     time.Duration
+	MinPriority Priority
+
+	mutex  sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewForwardHandler returns a ForwardHandler which sends records to the
+// given TCP address. An empty address uses DefaultForwardAddress.
+func NewForwardHandler(ad string) *ForwardHandler {
+	if ad == "" {
+		ad = DefaultForwardAddress
+	}
+
+	h := &ForwardHandler{
+		Network: "tcp",
+		Address: ad,
+		Timeout: DefaultForwardTimeout,
+	}
+	h.Batcher = newBatcher(h.send)
+	h.Spool = true
+
+	return h
+}
+
+// Enabled implements the Handler interface.
+func (fo *ForwardHandler) Enabled(lo Logger, pr Priority) bool {
+	return pr >= fo.MinPriority
+}
+
+// Handle implements the Handler interface. The record is queued and sent
+// in the background.
+func (fo *ForwardHandler) Handle(re Record) error {
+	return fo.enqueue(re)
+}
+
+// Close sends all queued records, stops the handler and closes the
+// connection.
+func (fo *ForwardHandler) Close() error {
+	err := fo.Batcher.Close()
+
+	fo.mutex.Lock()
+	fo.disconnect()
+	fo.mutex.Unlock()
+
+	return err
+}
+
+func (fo *ForwardHandler) send(re []Record) (err error) {
+	var t []Logger
+	g := make(map[Logger][]byte)
+	c := make(map[Logger]int)
+
+	for i := range re {
+		r := &re[i]
+
+		e, x := g[r.Logger]
+		if !x {
+			t = append(t, r.Logger)
+		}
+
+		g[r.Logger] = appendForwardEntry(e, r)
+		c[r.Logger]++
+	}
+
+	fo.mutex.Lock()
+	defer fo.mutex.Unlock()
+
+	for i, l := range t {
+		err = fo.write(l, g[l], c[l])
+		if err == nil {
+			continue
+		}
+
+		fo.disconnect()
+
+		// Only the records of the tags which were not sent yet are
+		// retried so the earlier tags are not delivered twice.
+		if i > 0 {
+			err = partialError{error: err, records: forwardRest(re, t[i:])}
+		}
+
+		return
+	}
+
+	return
+}
+
+// forwardRest returns the records of the given loggers.
+func forwardRest(re []Record, lo []Logger) []Record {
+	m := make(map[Logger]bool, len(lo))
+	for _, l := range lo {
+		m[l] = true
+	}
+
+	var r []Record
+	for _, v := range re {
+		if m[v.Logger] {
+			r = append(r, v)
+		}
+	}
+
+	return r
+}
+
+// appendForwardEntry appends the record as an entry of a PackedForward
+// message.
+func appendForwardEntry(b []byte, re *Record) []byte {
+	n := 3
+	if re.TraceID != "" {
+		n += 2
+	}
+
+	for _, f := range re.Fields {
+		if !traceField(re, f) {
+			n++
+		}
+	}
+
+	b = appendMsgpackArray(b, 2)
+	b = appendMsgpackEventTime(b, re.Time)
+	b = appendMsgpackMap(b, n)
+	b = appendMsgpackString(b, "priority")
+	b = appendMsgpackString(b, priorities[re.Priority])
+	b = appendMsgpackString(b, "logger")
+	b = appendMsgpackString(b, string(re.Logger))
+	b = appendMsgpackString(b, "message")
+	b = appendMsgpackString(b, re.Message)
+
+	if re.TraceID != "" {
+		b = appendMsgpackString(b, "trace_id")
+		b = appendMsgpackString(b, re.TraceID)
+		b = appendMsgpackString(b, "span_id")
+		b = appendMsgpackString(b, re.SpanID)
+	}
+
+	for _, f := range re.Fields {
+		if traceField(re, f) {
+			continue
+		}
+
+		b = appendMsgpackString(b, f.Key)
+		b = appendMsgpackString(b, f.Value)
+	}
+
+	return b
+}
+
+// write sends the entries as a PackedForward message and waits for the
+// acknowledgement if it is required.
+func (fo *ForwardHandler) write(ta Logger, en []byte, si int) (err error) {
+	if fo.conn == nil {
+		fo.conn, err = net.DialTimeout(fo.Network, fo.Address, fo.Timeout)
+		if err != nil {
+			return
+		}
+
+		fo.reader = bufio.NewReader(fo.conn)
+	}
+
+	var c string
+	if fo.RequireAck {
+		c = newChunkID()
+	}
+
+	b := appendForwardMessage(nil, ta, en, si, c)
+
+	if fo.Timeout > 0 {
+		fo.conn.SetDeadline(time.Now().Add(fo.Timeout))
+	}
+
+	_, err = fo.conn.Write(b)
+	if err != nil || !fo.RequireAck {
+		return
+	}
+
+	v, err := readMsgpack(fo.reader)
+	if err != nil {
+		return
+	}
+
+	m, _ := v.(map[string]interface{})
+	if m["ack"] != c {
+		err = errors.New("can not send records: wrong acknowledgement from server")
+		return
+	}
+
+	return
+}
+
+// appendForwardMessage appends a PackedForward message with the entries
+// of the tag. The chunk id is only added if it is not empty.
+func appendForwardMessage(b []byte, ta Logger, en []byte, si int, ch string) []byte {
+	n := 1
+	if ch != "" {
+		n = 2
+	}
+
+	b = appendMsgpackArray(b, 3)
+	b = appendMsgpackString(b, string(ta))
+	b = appendMsgpackBin(b, en)
+	b = appendMsgpackMap(b, n)
+	b = appendMsgpackString(b, "size")
+	b = appendMsgpackUint(b, uint64(si))
+
+	if ch != "" {
+		b = appendMsgpackString(b, "chunk")
+		b = appendMsgpackString(b, ch)
+	}
+
+	return b
+}
+
+func (fo *ForwardHandler) disconnect() {
+	if fo.conn == nil {
+		return
+	}
+
+	fo.conn.Close()
+	fo.conn = nil
+	fo.reader = nil
+}
+
+func newChunkID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return base64.StdEncoding.EncodeToString(b)
+}
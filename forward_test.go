@@ -0,0 +1,344 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// forwardServer is a stand-in for a fluentd forward input. It decodes
+// PackedForward messages and acknowledges chunks. If drop is set the
+// connection of the first message with that tag is closed without an
+// acknowledgement.
+type forwardServer struct {
+	listener net.Listener
+
+	mutex   sync.Mutex
+	tags    []string
+	entries [][]interface{}
+	options []map[string]interface{}
+	drop    string
+}
+
+func newForwardServer(ad string) (*forwardServer, error) {
+	l, err := net.Listen("tcp", ad)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &forwardServer{listener: l}
+	go s.serve()
+
+	return s, nil
+}
+
+func (se *forwardServer) serve() {
+	for {
+		c, err := se.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go se.handle(c)
+	}
+}
+
+func (se *forwardServer) handle(co net.Conn) {
+	defer co.Close()
+
+	r := bufio.NewReader(co)
+
+	for {
+		v, err := readMsgpack(r)
+		if err != nil {
+			return
+		}
+
+		m := v.([]interface{})
+
+		se.mutex.Lock()
+		d := se.drop != "" && se.drop == m[0]
+		if d {
+			se.drop = ""
+		}
+		se.mutex.Unlock()
+
+		if d {
+			return
+		}
+
+		o := m[2].(map[string]interface{})
+
+		var e [][]interface{}
+		b := bufio.NewReader(bytes.NewReader(m[1].([]byte)))
+		for {
+			v, err := readMsgpack(b)
+			if err != nil {
+				break
+			}
+
+			e = append(e, v.([]interface{}))
+		}
+
+		se.mutex.Lock()
+		se.tags = append(se.tags, m[0].(string))
+		se.entries = append(se.entries, e...)
+		se.options = append(se.options, o)
+		se.mutex.Unlock()
+
+		if c, ok := o["chunk"].(string); ok {
+			a := appendMsgpackMap(nil, 1)
+			a = appendMsgpackString(a, "ack")
+			a = appendMsgpackString(a, c)
+			co.Write(a)
+		}
+	}
+}
+
+func (se *forwardServer) Close() error {
+	return se.listener.Close()
+}
+
+func TestForwardHandler(t *testing.T) {
+	l := New(namet + ".ForwardHandler")
+
+	s, err := newForwardServer("127.0.0.1:0")
+	if err != nil {
+		l.Critical("Can not listen: ", err)
+		t.FailNow()
+	}
+	defer s.Close()
+
+	h := NewForwardHandler(s.listener.Addr().String())
+	h.RequireAck = true
+
+	n := New(namet + ".ForwardHandler.Test")
+	n.SetLevel(Info)
+	n.SetOutput(ioutil.Discard)
+	n.AddHandler(h)
+
+	m := New(namet + ".ForwardHandler.Other")
+	m.SetLevel(Info)
+	m.SetOutput(ioutil.Discard)
+	m.AddHandler(h)
+
+	ctx := WithTraceContext(WithUser(context.Background(), "alice"), TraceContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+	})
+
+	n.WarningCtx(ctx, "Test1")
+	m.Info("Test2")
+	n.Error("Test3")
+
+	err = h.Close()
+	if err != nil {
+		l.Critical("Can not close handler: ", err)
+		t.FailNow()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.tags) != 2 || s.tags[0] != string(n) || s.tags[1] != string(m) {
+		l.Critical("GOT: ", s.tags, ", EXPECTED: ", []Logger{n, m})
+		t.FailNow()
+	}
+
+	if s.options[0]["size"] != uint64(2) || s.options[1]["size"] != uint64(1) || s.options[0]["chunk"] == nil {
+		l.Critical("Wrong options: ", s.options)
+		t.Fail()
+	}
+
+	if len(s.entries) != 3 {
+		l.Critical("GOT: ", len(s.entries), " entries, EXPECTED: 3")
+		t.FailNow()
+	}
+
+	if _, ok := s.entries[0][0].(time.Time); !ok {
+		l.Critical("Wrong time: ", s.entries[0][0])
+		t.Fail()
+	}
+
+	r := s.entries[0][1].(map[string]interface{})
+	v := map[string]interface{}{
+		"priority": "Warning",
+		"logger":   string(n),
+		"message":  "Test1",
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":  "00f067aa0ba902b7",
+		"user":     "alice",
+	}
+
+	for k, e := range v {
+		if r[k] != e {
+			l.Critical("GOT: ", r[k], ", EXPECTED: ", e, " for ", k)
+			t.Fail()
+		}
+	}
+
+	for i, e := range []string{"Test1", "Test3", "Test2"} {
+		o := s.entries[i][1].(map[string]interface{})["message"]
+		if o != e {
+			l.Critical("GOT: ", o, ", EXPECTED: ", e)
+			t.Fail()
+		}
+	}
+}
+
+func TestForwardHandlerSpool(t *testing.T) {
+	l := New(namet + ".ForwardHandlerSpool")
+
+	c, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		l.Critical("Can not listen: ", err)
+		t.FailNow()
+	}
+	a := c.Addr().String()
+	c.Close()
+
+	h := NewForwardHandler(a)
+	h.RequireAck = true
+	h.Retries = 1
+	h.Backoff = time.Millisecond
+	h.BatchAge = time.Hour
+	h.Timeout = time.Second
+
+	for _, v := range []string{"Test1", "Test2"} {
+		h.Handle(Record{Logger: namet + ".ForwardHandlerSpool.Test", Priority: Info, Message: v})
+	}
+
+	err = h.Flush()
+	if err == nil {
+		l.Critical("Flush should fail without a server")
+		t.Fail()
+	}
+
+	if h.Dropped() != 0 {
+		l.Critical("GOT: ", h.Dropped(), ", EXPECTED: ", 0)
+		t.Fail()
+	}
+
+	s, err := newForwardServer(a)
+	if err != nil {
+		l.Critical("Can not listen: ", err)
+		t.FailNow()
+	}
+	defer s.Close()
+
+	s.drop = namet + ".ForwardHandlerSpool.Test"
+
+	err = h.Close()
+	if err != nil {
+		l.Critical("Can not close handler: ", err)
+		t.FailNow()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.entries) != 2 {
+		l.Critical("GOT: ", len(s.entries), " entries, EXPECTED: 2")
+		t.FailNow()
+	}
+
+	for i, e := range []string{"Test1", "Test2"} {
+		o := s.entries[i][1].(map[string]interface{})["message"]
+		if o != e {
+			l.Critical("GOT: ", o, ", EXPECTED: ", e)
+			t.Fail()
+		}
+	}
+}
+
+func TestForwardHandlerPartial(t *testing.T) {
+	l := New(namet + ".ForwardHandlerPartial")
+
+	s, err := newForwardServer("127.0.0.1:0")
+	if err != nil {
+		l.Critical("Can not listen: ", err)
+		t.FailNow()
+	}
+	defer s.Close()
+
+	a := Logger(namet + ".ForwardHandlerPartial.First")
+	b := Logger(namet + ".ForwardHandlerPartial.Second")
+	s.drop = string(b)
+
+	h := NewForwardHandler(s.listener.Addr().String())
+	h.RequireAck = true
+	h.Retries = 1
+	h.Backoff = time.Millisecond
+	h.BatchAge = time.Hour
+
+	h.Handle(Record{Logger: a, Priority: Info, Message: "Test1"})
+	h.Handle(Record{Logger: b, Priority: Info, Message: "Test2"})
+
+	err = h.Close()
+	if err != nil {
+		l.Critical("Can not close handler: ", err)
+		t.FailNow()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.tags) != 2 || s.tags[0] != string(a) || s.tags[1] != string(b) {
+		l.Critical("GOT: ", s.tags, ", EXPECTED: ", []Logger{a, b})
+		t.Fail()
+	}
+}
+
+func TestForwardMessage(t *testing.T) {
+	l := New(namet + ".ForwardMessage")
+
+	r := Record{
+		Time:     time.Unix(1500000000, 123),
+		Logger:   "a",
+		Priority: Info,
+		Message:  "hi",
+	}
+
+	o := appendForwardMessage(nil, r.Logger, appendForwardEntry(nil, &r), 1, "")
+	v := []byte("\x93\xa1a\xc4\x2e" +
+		"\x92\xd7\x00\x59\x68\x2f\x00\x00\x00\x00\x7b" +
+		"\x83\xa8priority\xa4Info\xa6logger\xa1a\xa7message\xa2hi" +
+		"\x81\xa4size\x01")
+
+	if !bytes.Equal(o, v) {
+		l.Critical("GOT: ", fmt.Sprintf("% x", o), ", EXPECTED: ", fmt.Sprintf("% x", v))
+		t.Fail()
+	}
+}
+
+func TestForwardEntryTrace(t *testing.T) {
+	l := New(namet + ".ForwardEntryTrace")
+
+	r := Record{
+		Logger:  "Test",
+		Message: "Test",
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Fields:  []Field{{FieldTraceID, "abc"}, {FieldUser, "alice"}},
+	}
+
+	b := bufio.NewReader(bytes.NewReader(appendForwardEntry(nil, &r)))
+
+	e, err := readMsgpack(b)
+	if err != nil || b.Buffered() != 0 {
+		l.Critical("Can not read forward entry: ", err, ", ", b.Buffered(), " bytes left")
+		t.FailNow()
+	}
+
+	m := e.([]interface{})[1].(map[string]interface{})
+	if len(m) != 6 || m["trace_id"] != r.TraceID || m["user"] != "alice" {
+		l.Critical("GOT: ", m, ", EXPECTED one trace_id")
+		t.Fail()
+	}
+}
@@ -0,0 +1,316 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// DefaultGELFChunkSize is the default maximum size of a UDP datagram sent
+// by a GELFWriter.
+const DefaultGELFChunkSize = 1420
+
+const (
+	gelfchunkheader = 12
+	gelfmaxchunks   = 128
+)
+
+// gelflevels maps the priorities to syslog levels.
+var gelflevels = map[Priority]int{
+	Trace:     7,
+	Debug:     7,
+	Info:      6,
+	Notice:    5,
+	Warning:   4,
+	Error:     3,
+	Critical:  2,
+	Alert:     1,
+	Emergency: 0,
+}
+
+var (
+	gelfhost string
+	gelfonce sync.Once
+)
+
+// GELFEncoder formats every message as a GELF 1.1 message for Graylog.
+// The priority is mapped to the syslog level, the name of the logger is
+// sent as "_logger", the trace context as "_trace_id" and "_span_id" and
+// the fields of the record as additional fields prefixed with "_".
+// Characters which are not allowed in field names are replaced by "_". An
+// empty Host uses the hostname of the machine.
+//
+// The messages are not terminated, use a GELFWriter to send them.
+type GELFEncoder struct {
+	Host string
+}
+
+// Encode implements the Encoder interface.
+func (en GELFEncoder) Encode(b []byte, re *Record, nc bool) []byte {
+	h := en.Host
+	if h == "" {
+		gelfonce.Do(func() {
+			gelfhost, _ = os.Hostname()
+		})
+
+		h = gelfhost
+	}
+
+	b = append(b, `{"version":"1.1","host":`...)
+	b = appendJSONString(b, h)
+	b = append(b, `,"short_message":`...)
+	b = appendJSONString(b, re.Message)
+	b = append(b, `,"timestamp":`...)
+	b = strconv.AppendInt(b, re.Time.Unix(), 10)
+	b = append(b, '.')
+	m := re.Time.Nanosecond() / 1e6
+	b = append(b, byte('0'+m/100), byte('0'+m/10%10), byte('0'+m%10))
+	b = append(b, `,"level":`...)
+	b = strconv.AppendInt(b, int64(gelflevels[re.Priority]), 10)
+	b = append(b, `,"_logger":`...)
+	b = appendJSONString(b, string(re.Logger))
+
+	if re.TraceID != "" {
+		b = append(b, `,"_trace_id":`...)
+		b = appendJSONString(b, re.TraceID)
+		b = append(b, `,"_span_id":`...)
+		b = appendJSONString(b, re.SpanID)
+	}
+
+	for _, f := range re.Fields {
+		if traceField(re, f) {
+			continue
+		}
+
+		b = append(b, `,"_`...)
+		b = appendGELFKey(b, f.Key)
+		b = append(b, `":`...)
+		b = appendJSONString(b, f.Value)
+	}
+
+	return append(b, '}')
+}
+
+// appendGELFKey appends the key with every character which is not allowed
+// in a GELF field name replaced by "_". The key "id" is reserved and
+// becomes "id_".
+func appendGELFKey(b []byte, k string) []byte {
+	if k == "id" {
+		return append(b, "id_"...)
+	}
+
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '.', c == '-':
+			b = append(b, c)
+		default:
+			b = append(b, '_')
+		}
+	}
+
+	return b
+}
+
+// GELFCompression is the compression used by a GELFWriter for UDP.
+type GELFCompression int
+
+// The compressions supported by GELFWriter.
+const (
+	GELFNoCompression GELFCompression = iota
+	GELFGzip
+	GELFZlib
+)
+
+// GELFWriter sends GELF messages, as encoded by GELFEncoder, to a Graylog
+// input. Every call to Write sends one message. With "udp" messages are
+// compressed with the given Compression and split into chunks if they are
+// bigger than ChunkSize. With "tcp" messages are terminated by a null
+// byte and are never compressed. A broken TCP connection is reopened on
+// the next write.
+type GELFWriter struct {
+	Network     string
+	Address     string
+	Compression GELFCompression
+	ChunkSize   int
+
+	mutex  sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// NewGELFWriter returns a GELFWriter which is connected to the address
+// with the given network, "udp" or "tcp".
+func NewGELFWriter(ne, ad string) (wr *GELFWriter, err error) {
+	if ne != "udp" && ne != "tcp" {
+		err = errors.New("can not create gelf writer: unknown network " + ne)
+		return
+	}
+
+	wr = &GELFWriter{
+		Network:   ne,
+		Address:   ad,
+		ChunkSize: DefaultGELFChunkSize,
+	}
+
+	wr.conn, err = net.Dial(ne, ad)
+	if err != nil {
+		wr = nil
+		return
+	}
+
+	return
+}
+
+// Write implements the io.Writer interface. A trailing newline is
+// removed from the message.
+func (wr *GELFWriter) Write(p []byte) (n int, err error) {
+	m := bytes.TrimSuffix(p, []byte("\n"))
+
+	wr.mutex.Lock()
+	defer wr.mutex.Unlock()
+
+	if wr.closed {
+		err = errors.New("the gelf writer is closed")
+		return
+	}
+
+	if wr.Network == "tcp" {
+		err = wr.writeTCP(m)
+	} else {
+		err = wr.writeUDP(m)
+	}
+
+	if err != nil {
+		return
+	}
+
+	n = len(p)
+	return
+}
+
+func (wr *GELFWriter) writeTCP(m []byte) (err error) {
+	b := make([]byte, len(m)+1)
+	copy(b, m)
+
+	if wr.conn == nil {
+		wr.conn, err = net.Dial(wr.Network, wr.Address)
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = wr.conn.Write(b)
+	if err == nil {
+		return
+	}
+
+	wr.conn.Close()
+
+	wr.conn, err = net.Dial(wr.Network, wr.Address)
+	if err != nil {
+		return
+	}
+
+	_, err = wr.conn.Write(b)
+	if err != nil {
+		wr.conn.Close()
+		wr.conn = nil
+	}
+
+	return
+}
+
+func (wr *GELFWriter) writeUDP(m []byte) (err error) {
+	m, err = compressGELF(m, wr.Compression)
+	if err != nil {
+		return
+	}
+
+	s := wr.ChunkSize
+	if s <= gelfchunkheader {
+		s = DefaultGELFChunkSize
+	}
+
+	if len(m) <= s {
+		_, err = wr.conn.Write(m)
+		return
+	}
+
+	s -= gelfchunkheader
+	c := (len(m) + s - 1) / s
+	if c > gelfmaxchunks {
+		err = errors.New("can not send gelf message: too many chunks " + strconv.Itoa(c))
+		return
+	}
+
+	b := make([]byte, gelfchunkheader+s)
+	b[0], b[1] = 0x1e, 0x0f
+	rand.Read(b[2:10])
+	b[11] = byte(c)
+
+	for i := 0; i < c; i++ {
+		b[10] = byte(i)
+
+		e := (i + 1) * s
+		if e > len(m) {
+			e = len(m)
+		}
+
+		n := copy(b[gelfchunkheader:], m[i*s:e])
+
+		_, err = wr.conn.Write(b[:gelfchunkheader+n])
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func compressGELF(m []byte, co GELFCompression) ([]byte, error) {
+	var b bytes.Buffer
+
+	switch co {
+	case GELFGzip:
+		w := gzip.NewWriter(&b)
+		w.Write(m)
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case GELFZlib:
+		w := zlib.NewWriter(&b)
+		w.Write(m)
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return m, nil
+	}
+
+	return b.Bytes(), nil
+}
+
+// Close closes the connection of the writer.
+func (wr *GELFWriter) Close() (err error) {
+	wr.mutex.Lock()
+	defer wr.mutex.Unlock()
+
+	wr.closed = true
+
+	if wr.conn == nil {
+		return
+	}
+
+	err = wr.conn.Close()
+	wr.conn = nil
+
+	return
+}
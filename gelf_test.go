@@ -0,0 +1,234 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGELFEncoder(t *testing.T) {
+	l := New(namet + ".GELFEncoder")
+
+	r := Record{
+		Time:     time.Date(2020, 1, 2, 3, 4, 5, 67e6, time.UTC),
+		Logger:   "Test",
+		Priority: Warning,
+		Message:  "Test \"Message\"",
+		TraceID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:   "00f067aa0ba902b7",
+		Fields:   []Field{{"user", "alice"}, {"id", "1"}, {"a b", "c"}},
+	}
+
+	v := `{"version":"1.1","host":"host","short_message":"Test \"Message\"","timestamp":1577934245.067,"level":4,"_logger":"Test","_trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","_span_id":"00f067aa0ba902b7","_user":"alice","_id_":"1","_a_b":"c"}`
+
+	o := string(GELFEncoder{Host: "host"}.Encode(nil, &r, true))
+	if o != v {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+}
+
+// readGELFChunks reads datagrams from the connection and returns the
+// reassembled message.
+func readGELFChunks(co net.PacketConn) ([]byte, error) {
+	b := make([]byte, 65536)
+	var c [][]byte
+
+	for {
+		co.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		n, _, err := co.ReadFrom(b)
+		if err != nil {
+			return nil, err
+		}
+
+		if n < 2 || b[0] != 0x1e || b[1] != 0x0f {
+			return append([]byte(nil), b[:n]...), nil
+		}
+
+		if c == nil {
+			c = make([][]byte, b[11])
+		}
+		c[b[10]] = append([]byte(nil), b[12:n]...)
+
+		d := true
+		for _, v := range c {
+			d = d && v != nil
+		}
+
+		if d {
+			return bytes.Join(c, nil), nil
+		}
+	}
+}
+
+func TestGELFWriterUDP(t *testing.T) {
+	l := New(namet + ".GELFWriterUDP")
+
+	p, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		l.Critical("Can not listen: ", err)
+		t.FailNow()
+	}
+	defer p.Close()
+
+	w, err := NewGELFWriter("udp", p.LocalAddr().String())
+	if err != nil {
+		l.Critical("Can not create writer: ", err)
+		t.FailNow()
+	}
+	defer w.Close()
+
+	n := New(namet + ".GELFWriterUDP.Test")
+	n.SetLevel(Info)
+	n.SetOutput(ioutil.Discard)
+	n.AddOutput(Output{Writer: w, Encoder: GELFEncoder{Host: "host"}, MinPriority: Trace, MaxPriority: Emergency})
+
+	for _, c := range []struct {
+		Compression GELFCompression
+		ChunkSize   int
+		Message     string
+	}{
+		{GELFNoCompression, DefaultGELFChunkSize, "Test"},
+		{GELFZlib, DefaultGELFChunkSize, "Test"},
+		{GELFNoCompression, 100, strings.Repeat("Test", 200)},
+		{GELFGzip, 30, strings.Repeat("Test", 200)},
+	} {
+		w.Compression = c.Compression
+		w.ChunkSize = c.ChunkSize
+
+		n.Error(c.Message)
+
+		b, err := readGELFChunks(p)
+		if err != nil {
+			l.Critical("Can not read message: ", err)
+			t.FailNow()
+		}
+
+		switch c.Compression {
+		case GELFGzip:
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			if err == nil {
+				b, err = ioutil.ReadAll(r)
+			}
+			if err != nil {
+				l.Critical("Can not decompress: ", err)
+				t.FailNow()
+			}
+		case GELFZlib:
+			r, err := zlib.NewReader(bytes.NewReader(b))
+			if err == nil {
+				b, err = ioutil.ReadAll(r)
+			}
+			if err != nil {
+				l.Critical("Can not decompress: ", err)
+				t.FailNow()
+			}
+		}
+
+		var m map[string]interface{}
+		err = json.Unmarshal(b, &m)
+		if err != nil {
+			l.Critical("Can not parse message: ", err)
+			t.FailNow()
+		}
+
+		if m["short_message"] != c.Message || m["level"] != 3.0 || m["_logger"] != string(n) {
+			l.Critical("Wrong message: ", m)
+			t.Fail()
+		}
+	}
+}
+
+func TestGELFWriterTCP(t *testing.T) {
+	l := New(namet + ".GELFWriterTCP")
+
+	s, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		l.Critical("Can not listen: ", err)
+		t.FailNow()
+	}
+	defer s.Close()
+
+	c := make(chan []string, 1)
+	go func() {
+		co, err := s.Accept()
+		if err != nil {
+			c <- nil
+			return
+		}
+		defer co.Close()
+
+		r := bufio.NewReader(co)
+
+		var o []string
+		for len(o) < 2 {
+			m, err := r.ReadString(0)
+			if err != nil {
+				break
+			}
+
+			o = append(o, strings.TrimSuffix(m, "\x00"))
+		}
+
+		c <- o
+	}()
+
+	w, err := NewGELFWriter("tcp", s.Addr().String())
+	if err != nil {
+		l.Critical("Can not create writer: ", err)
+		t.FailNow()
+	}
+	defer w.Close()
+
+	n := New(namet + ".GELFWriterTCP.Test")
+	n.SetLevel(Info)
+	n.SetOutput(ioutil.Discard)
+	n.AddOutput(Output{Writer: w, Encoder: GELFEncoder{Host: "host"}, MinPriority: Trace, MaxPriority: Emergency})
+
+	n.Info("Test1")
+	n.Warning("Test2")
+
+	var o []string
+	select {
+	case o = <-c:
+	case <-time.After(5 * time.Second):
+	}
+
+	if len(o) != 2 {
+		l.Critical("GOT: ", o, ", EXPECTED: 2 messages")
+		t.FailNow()
+	}
+
+	for i, v := range []string{`"short_message":"Test1"`, `"short_message":"Test2"`} {
+		if !strings.Contains(o[i], v) || !strings.HasSuffix(o[i], "}") {
+			l.Critical("GOT: ", o[i], ", EXPECTED: ", v)
+			t.Fail()
+		}
+	}
+}
+
+func TestGELFEncoderTrace(t *testing.T) {
+	l := New(namet + ".GELFEncoderTrace")
+
+	r := Record{
+		Logger:  "Test",
+		Message: "Test",
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Fields:  []Field{{FieldTraceID, "abc"}, {FieldUser, "alice"}},
+	}
+
+	o := string(GELFEncoder{Host: "host"}.Encode(nil, &r, true))
+	if strings.Count(o, `"_trace_id"`) != 1 || !strings.Contains(o, `"_trace_id":"`+r.TraceID+`"`) {
+		l.Critical("GOT: ", o, ", EXPECTED one _trace_id")
+		t.Fail()
+	}
+}
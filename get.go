@@ -9,24 +9,22 @@ func getParent(lo Logger) (log Logger) {
 		return
 	}
 
-	s := strings.Split(string(lo), defseperator)
+	s := string(lo)
+	i := strings.LastIndex(s, defseperator)
 
 	// Return root if first level logger
-	if len(s) == 1 {
+	if i < 0 {
 		log = defroot
 		return
 	}
 
 	// Return root if parent is empty
-	if s[0] == "" {
+	if strings.HasPrefix(s, defseperator) {
 		log = defroot
 		return
 	}
 
-	l := len(s) - 1
-	z := s[0:l]
-
-	log = Logger(strings.Join(z, defseperator))
+	log = Logger(s[:i])
 
 	return
 }
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"io"
+)
+
+// WriterHandler is a Handler which encodes records with its Encoder and
+// writes them to its Writer. A nil Encoder uses a TextEncoder with the
+// default format. Writes are serialized with all outputs of the loggers
+// using the same writer.
+type WriterHandler struct {
+	Writer      io.Writer
+	Encoder     Encoder
+	MinPriority Priority
+	NoColor     bool
+
+	state writer
+}
+
+// NewWriterHandler returns a WriterHandler which writes all records to
+// the writer encoded with the given encoder.
+func NewWriterHandler(wr io.Writer, en Encoder) *WriterHandler {
+	return &WriterHandler{
+		Writer:  wr,
+		Encoder: en,
+	}
+}
+
+// Enabled implements the Handler interface.
+func (wr *WriterHandler) Enabled(lo Logger, pr Priority) bool {
+	return pr >= wr.MinPriority
+}
+
+// Handle implements the Handler interface.
+func (wr *WriterHandler) Handle(re Record) error {
+	b := getBuffer()
+	defer putBuffer(b)
+
+	e := wr.Encoder
+	if e == nil {
+		e = TextEncoder{Format: Format(format)}
+	}
+
+	b.line = e.Encode(b.line[:0], &re, wr.NoColor)
+
+	w := list.lookupWriter(wr.Writer)
+	if w == nil {
+		w = &wr.state
+	}
+
+	return writeOutput(w, wr.Writer, b.line)
+}
@@ -0,0 +1,209 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Keys of the fields added by HTTPMiddleware.
+const (
+	FieldMethod     = "method"
+	FieldPath       = "path"
+	FieldStatus     = "status"
+	FieldBytes      = "bytes"
+	FieldDuration   = "duration"
+	FieldRemoteAddr = "remote_addr"
+	FieldUserAgent  = "user_agent"
+)
+
+// combinedtime is the time format of the Apache combined log format.
+const combinedtime = "02/Jan/2006:15:04:05 -0700"
+
+// HTTPOptions configures the access log of HTTPMiddleware.
+type HTTPOptions struct {
+	// RemoteAddr adds the remote address of the request.
+	RemoteAddr bool
+	// UserAgent adds the user agent of the request.
+	UserAgent bool
+	// Combined writes the message in the Apache combined log format. The
+	// format of the logger should be set to "{{.Message}}\n" to get lines
+	// which can be read by tools for that format.
+	Combined bool
+}
+
+// HTTPMiddleware returns a middleware which logs every request to the
+// given logger after it was served. The method, path, status, number of
+// bytes written and the duration of the request are added as fields and
+// are part of the message. Responses with a 5xx status are logged with
+// the Error priority, responses with a 4xx status with the Warning
+// priority and all other responses with the Info priority. The context
+// of the request is used for logging, see LogCtx.
+func HTTPMiddleware(lo Logger, op HTTPOptions) func(http.Handler) http.Handler {
+	return func(ne http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s := time.Now()
+			a := &accessWriter{ResponseWriter: w}
+
+			ne.ServeHTTP(a, r)
+
+			logAccess(lo, op, r, a, s)
+		})
+	}
+}
+
+func logAccess(lo Logger, op HTTPOptions, r *http.Request, a *accessWriter, s time.Time) {
+	d := time.Since(s)
+
+	t := a.status
+	if t == 0 {
+		t = http.StatusOK
+	}
+
+	p := Info
+	switch {
+	case t >= 500:
+		p = Error
+	case t >= 400:
+		p = Warning
+	}
+
+	f := []Field{
+		{Key: FieldMethod, Value: r.Method},
+		{Key: FieldPath, Value: r.URL.RequestURI()},
+		{Key: FieldStatus, Value: strconv.Itoa(t)},
+		{Key: FieldBytes, Value: strconv.FormatInt(a.bytes, 10)},
+		{Key: FieldDuration, Value: d.String()},
+	}
+
+	if op.RemoteAddr {
+		f = append(f, Field{Key: FieldRemoteAddr, Value: r.RemoteAddr})
+	}
+
+	if op.UserAgent {
+		f = append(f, Field{Key: FieldUserAgent, Value: r.UserAgent()})
+	}
+
+	var m string
+	if op.Combined {
+		m = formatCombined(r, t, a.bytes, s)
+	} else {
+		m = r.Method + " " + r.URL.RequestURI() + " " + strconv.Itoa(t) + " " + strconv.FormatInt(a.bytes, 10) + " " + d.String()
+	}
+
+	logMessageCtx(r.Context(), lo, p, f, m)
+}
+
+// formatCombined formats the request in the Apache combined log format.
+func formatCombined(r *http.Request, st int, by int64, ti time.Time) string {
+	h, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		h = r.RemoteAddr
+	}
+
+	u := "-"
+	if r.URL.User != nil && r.URL.User.Username() != "" {
+		u = r.URL.User.Username()
+	} else if n, _, ok := r.BasicAuth(); ok && n != "" {
+		u = n
+	}
+
+	b := "-"
+	if by > 0 {
+		b = strconv.FormatInt(by, 10)
+	}
+
+	return orDash(h) + " - " + u + " [" + ti.Format(combinedtime) + "] " +
+		strconv.Quote(r.Method+" "+r.URL.RequestURI()+" "+r.Proto) + " " +
+		strconv.Itoa(st) + " " + b + " " +
+		strconv.Quote(orDash(r.Referer())) + " " + strconv.Quote(orDash(r.UserAgent()))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// accessWriter records the status and the number of bytes written of a
+// response.
+type accessWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (ac *accessWriter) WriteHeader(st int) {
+	if ac.status == 0 {
+		ac.status = st
+	}
+
+	ac.ResponseWriter.WriteHeader(st)
+}
+
+func (ac *accessWriter) Write(p []byte) (int, error) {
+	if ac.status == 0 {
+		ac.status = http.StatusOK
+	}
+
+	n, err := ac.ResponseWriter.Write(p)
+	ac.bytes += int64(n)
+
+	return n, err
+}
+
+// Flush implements http.Flusher if the wrapped writer does.
+func (ac *accessWriter) Flush() {
+	f, ok := ac.ResponseWriter.(http.Flusher)
+	if ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the wrapped writer does. A hijacked
+// response is logged with the status 101.
+func (ac *accessWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := ac.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijacking")
+	}
+
+	c, b, err := h.Hijack()
+	if err == nil && ac.status == 0 {
+		ac.status = http.StatusSwitchingProtocols
+	}
+
+	return c, b, err
+}
+
+// ReadFrom implements io.ReaderFrom so the wrapped writer can still use
+// sendfile.
+func (ac *accessWriter) ReadFrom(re io.Reader) (int64, error) {
+	if ac.status == 0 {
+		ac.status = http.StatusOK
+	}
+
+	var n int64
+	var err error
+
+	r, ok := ac.ResponseWriter.(io.ReaderFrom)
+	if ok {
+		n, err = r.ReadFrom(re)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{ac.ResponseWriter}, re)
+	}
+	ac.bytes += n
+
+	return n, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (ac *accessWriter) Unwrap() http.ResponseWriter {
+	return ac.ResponseWriter
+}
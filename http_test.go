@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	l := New(namet + ".HTTPMiddleware")
+
+	n := New(namet + ".HTTPMiddleware.Test")
+	n.SetLevel(Info)
+	n.SetNoColor(true)
+	n.SetFormat("{{.Priority}} {{.Message}}{{.Fields}}\n")
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+
+	h := HTTPMiddleware(n, HTTPOptions{UserAgent: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte("Test"))
+		}
+	}))
+
+	for _, v := range []string{"/?q=1", "/missing", "/error"} {
+		r := httptest.NewRequest("GET", v, nil)
+		r.Header.Set("User-Agent", "test")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	v := regexp.MustCompile(`^Info GET /\?q=1 200 4 \S+ method=GET path="/\?q=1" status=200 bytes=4 duration=\S+ user_agent=test
+Warning GET /missing 404 19 \S+ method=GET path=/missing status=404 bytes=19 duration=\S+ user_agent=test
+Error GET /error 500 0 \S+ method=GET path=/error status=500 bytes=0 duration=\S+ user_agent=test
+$`)
+
+	o := b.String()
+	if !v.MatchString(o) {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+}
+
+func TestHTTPMiddlewareCombined(t *testing.T) {
+	l := New(namet + ".HTTPMiddleware.Combined")
+
+	n := New(namet + ".HTTPMiddleware.Combined.Test")
+	n.SetLevel(Info)
+	n.SetFormat("{{.Message}}\n")
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+
+	h := HTTPMiddleware(n, HTTPOptions{Combined: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Test"))
+	}))
+
+	r := httptest.NewRequest("GET", "/index.html", nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	r.SetBasicAuth("frank", "secret")
+	r.Header.Set("Referer", "http://example.com/")
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	v := regexp.MustCompile(`^127\.0\.0\.1 - frank \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /index.html HTTP/1.1" 200 4 "http://example.com/" "Mozilla/5.0"
+$`)
+
+	o := b.String()
+	if !v.MatchString(o) {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+}
+
+func TestHTTPMiddlewareHijack(t *testing.T) {
+	l := New(namet + ".HTTPMiddlewareHijack")
+
+	n := New(namet + ".HTTPMiddlewareHijack.Test")
+	n.SetLevel(Info)
+	n.SetNoColor(true)
+	n.SetFormat("{{.Message}}\n")
+
+	var b syncBuffer
+	n.SetOutput(&b)
+
+	s := httptest.NewServer(HTTPMiddleware(n, HTTPOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/file" {
+			f, ok := w.(io.ReaderFrom)
+			if !ok {
+				http.Error(w, "no io.ReaderFrom", http.StatusInternalServerError)
+				return
+			}
+
+			f.ReadFrom(strings.NewReader("Test"))
+			return
+		}
+
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "no http.Hijacker", http.StatusInternalServerError)
+			return
+		}
+
+		c, rw, err := h.Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer c.Close()
+
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nHijacked")
+		rw.Flush()
+	})))
+	defer s.Close()
+
+	for p, v := range map[string]string{"/": "Hijacked", "/file": "Test"} {
+		r, err := http.Get(s.URL + p)
+		if err != nil {
+			l.Critical("Can not get ", p, ": ", err)
+			t.FailNow()
+		}
+
+		o, _ := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+
+		if string(o) != v {
+			l.Critical("GOT: ", string(o), ", EXPECTED: ", v)
+			t.Fail()
+		}
+	}
+
+	v := regexp.MustCompile(`(?m)^GET / 101 0 \S+\nGET /file 200 4 \S+\n$|^GET /file 200 4 \S+\nGET / 101 0 \S+\n$`)
+
+	// The hijacked request is logged after the client got its response.
+	o := b.String()
+	for i := 0; i < 100 && !v.MatchString(o); i++ {
+		time.Sleep(10 * time.Millisecond)
+		o = b.String()
+	}
+
+	if !v.MatchString(o) {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+}
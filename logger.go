@@ -24,6 +24,12 @@ type Format string
 // sepperation with dots ('.'). The root logger has the name '.'.
 type Logger string
 
+// ErrorHandler is called with the name of the logger and the error when
+// a message could not be written to the output of the logger. The handler
+// may log the error, errors of messages it logs itself do not call the
+// handler again.
+type ErrorHandler func(lo Logger, err error)
+
 // Priority defines how important a log message is. Loggers will output
 // messages which are above their priority level.
 type Priority int
@@ -49,11 +55,11 @@ const (
 )
 
 var (
-	format     = "[{{.Time}} {{.Priority}} {{.Logger}}] - {{.Message}}.\n"
+	format     = "[{{.Time}} {{.Priority}} {{.Logger}}] - {{.Message}}.{{.Fields}}\n"
 	timeformat = time.RFC3339
 
 	priorities     map[Priority]string
-	list           loggers
+	list           *loggers
 	formattemplate template.Template
 
 	// SaveLoggerLevels will make the package save loggers which are only defined
@@ -97,6 +103,29 @@ func ImportLoggers(lo map[Logger]string) (err error) {
 	return
 }
 
+// ImportFilters adds the filters to the given Loggers. The filters are
+// parsed with ParseFilter.
+func ImportFilters(lo map[Logger][]string) (err error) {
+	if lo == nil {
+		err = errors.New("the filter map is nil")
+		return
+	}
+
+	for k, v := range lo {
+		for _, s := range v {
+			f, e := ParseFilter(s)
+			if e != nil {
+				err = e
+				return
+			}
+
+			AddFilter(k, f)
+		}
+	}
+
+	return
+}
+
 // New will return a logger with the given name.
 func New(na ...string) (log Logger) {
 	s := strings.Join(na, DefaultSepperator)
@@ -129,9 +158,18 @@ func SetLevel(lo Logger, pr Priority) (err error) {
 //
 // Message: The output message.
 //
+// Fields: The fields of the message as " key=value" pairs. Values
+// containing spaces or quotes are quoted.
+//
+// TraceID: The trace id of the message if it was logged with a trace
+// context.
+//
+// SpanID: The span id of the message if it was logged with a trace
+// context.
+//
 // The default Format is:
 //
-// "[{{.Time}} {{.Logger}} {{.Priority}}] - {{.Message}}.\n"
+// "[{{.Time}} {{.Logger}} {{.Priority}}] - {{.Message}}.{{.Fields}}\n"
 func SetFormat(lo Logger, fo Format) error {
 	return list.SetFormat(lo, fo)
 }
@@ -150,12 +188,136 @@ func SetNoColor(lo Logger, nc bool) {
 	list.SetNoColor(lo, nc)
 }
 
-// SetOutput sets the output parameter of the logger to the given
-// io.Writer. The default is os.Stderr.
+// SetRateLimiter sets the RateLimiter for the given logger. Messages are
+// checked against the limiter after the priority check. Setting nil
+// removes the limiter. By default messages are not limited.
+func SetRateLimiter(lo Logger, rl *RateLimiter) {
+	list.SetRateLimiter(lo, rl)
+}
+
+// SetSanitize sets the sanitize flag for the given logger. If true
+// newlines, carriage returns and other control characters in the logger
+// name, the message and the fields are escaped when they are formatted so
+// user supplied values can not forge lines or send escape sequences to
+// terminals. The colors of the priority are not affected. The default is
+// false.
+func SetSanitize(lo Logger, sa bool) {
+	list.SetSanitize(lo, sa)
+}
+
+// SetPropagate sets the propagate flag for the given logger. If true
+// messages of the logger are also passed to the outputs and handlers of
+// its parent. Messages keep propagating upwards as long as the parents
+// have the flag set too, up to the root logger. Messages are only passed
+// once to outputs and handlers which are shared between the loggers. The
+// priority levels of the parents are not checked but the priority range
+// of every output is. The default is false.
+func SetPropagate(lo Logger, pa bool) {
+	list.SetPropagate(lo, pa)
+}
+
+// SetOutput replaces all outputs of the logger with a single output
+// which writes to the given io.Writer. The default is os.Stderr.
+//
+// Every message is written with a single Write call. Writes are
+// serialized per writer so the writer does not have to be safe for
+// concurrent use, even if it is shared between loggers.
 func SetOutput(lo Logger, ou io.Writer) error {
 	return list.SetOutput(lo, ou)
 }
 
+// AddOutput adds the output to the outputs of the logger. Messages are
+// written to every output which accepts their priority.
+func AddOutput(lo Logger, ou Output) error {
+	return list.AddOutput(lo, ou)
+}
+
+// RemoveOutput removes all outputs writing to the given io.Writer from
+// the logger.
+func RemoveOutput(lo Logger, wr io.Writer) error {
+	return list.RemoveOutput(lo, wr)
+}
+
+// AddFilter adds the filter to the given logger. Filters are checked
+// after the priority level of the logger and records are dropped if any
+// filter returns false. Filters are inherited by child loggers.
+func AddFilter(lo Logger, fi Filter) error {
+	return list.AddFilter(lo, fi)
+}
+
+// ClearFilters removes all filters from the given logger.
+func ClearFilters(lo Logger) {
+	list.ClearFilters(lo)
+}
+
+// SetSampler sets the Sampler for records with the given priority of
+// the given logger. Samplers are checked after the filters. Setting nil
+// removes the sampler. Samplers are inherited by child loggers.
+func SetSampler(lo Logger, pr Priority, sa Sampler) error {
+	return list.SetSampler(lo, pr, sa)
+}
+
+// AddMiddleware adds the middleware to the given logger. Middleware runs
+// in the order it was added after the priority check of the logger and
+// before the filters. Middleware is inherited by child loggers.
+func AddMiddleware(lo Logger, mw Middleware) error {
+	return list.AddMiddleware(lo, mw)
+}
+
+// ClearMiddleware removes all middleware from the given logger.
+func ClearMiddleware(lo Logger) {
+	list.ClearMiddleware(lo)
+}
+
+// AddGlobalMiddleware adds the middleware for all loggers. Global
+// middleware runs before the middleware of the logger.
+func AddGlobalMiddleware(mw Middleware) error {
+	return list.AddGlobalMiddleware(mw)
+}
+
+// ClearGlobalMiddleware removes all global middleware.
+func ClearGlobalMiddleware() {
+	list.ClearGlobalMiddleware()
+}
+
+// SetRedactor sets the Redactor which will replace secrets in the
+// messages and fields of all loggers. It runs after the middleware and
+// before the filters. By default nothing is redacted. Setting nil
+// removes the redactor.
+func SetRedactor(rd *Redactor) {
+	list.SetRedactor(rd)
+}
+
+// AddHandler adds the handler to the given logger. Every record of the
+// logger which passes its priority level is passed to the handler if the
+// handler is enabled for it. Handlers are inherited by child loggers.
+func AddHandler(lo Logger, ha Handler) error {
+	return list.AddHandler(lo, ha)
+}
+
+// RemoveHandler removes the handler from the given logger.
+func RemoveHandler(lo Logger, ha Handler) error {
+	return list.RemoveHandler(lo, ha)
+}
+
+// SetErrorHandler sets the function which will be called when a message
+// could not be written to its output or a handler returned an error. By
+// default these errors are ignored. Setting nil removes the handler.
+// Errors of messages the handler logs itself do not call it again and
+// are only written to the fallback output. The handler can be called
+// concurrently.
+func SetErrorHandler(eh ErrorHandler) {
+	list.SetErrorHandler(eh)
+}
+
+// SetFallbackOutput sets the io.Writer which will receive messages that
+// could not be written to the output of their logger, for example
+// os.Stderr. By default there is no fallback. Setting nil removes the
+// fallback.
+func SetFallbackOutput(ou io.Writer) {
+	list.SetFallbackOutput(ou)
+}
+
 // ParsePriority tries to parse the priority by the given string.
 func ParsePriority(pr string) (Priority, error) {
 	for k, v := range priorities {
@@ -180,6 +342,14 @@ func NamePriority(pr Priority) (pri string, err error) {
 	return
 }
 
+// Enabled returns true if a message with the given priority would be
+// printed by the given logger.
+func Enabled(lo Logger, pr Priority) bool {
+	l := list.GetLogger(lo)
+
+	return l.Priority <= pr
+}
+
 func logMessage(lo Logger, pr Priority, me ...interface{}) {
 	l := list.GetLogger(lo)
 
@@ -187,12 +357,36 @@ func logMessage(lo Logger, pr Priority, me ...interface{}) {
 		return
 	}
 
+	if l.RateLimiter != nil && !l.RateLimiter.allow(lo, pr, 3) {
+		return
+	}
+
 	printMessage(l, pr, me...)
 }
 
+func logMessageFn(lo Logger, pr Priority, fn func() string) {
+	l := list.GetLogger(lo)
+
+	if l.Priority > pr {
+		return
+	}
+
+	if l.RateLimiter != nil && !l.RateLimiter.allow(lo, pr, 3) {
+		return
+	}
+
+	printString(l, pr, fn())
+}
+
+// Enabled returns true if a message with the given priority would be
+// printed by the Logger.
+func (lo Logger) Enabled(pr Priority) bool {
+	return Enabled(lo, pr)
+}
+
 // Log logs a message with the given priority.
 func (lo Logger) Log(pr Priority, me ...interface{}) {
-	logMessage(lo, pr, me)
+	logMessage(lo, pr, me...)
 }
 
 // Trace logs a message with the Trace priority.
@@ -240,6 +434,68 @@ func (lo Logger) Emergency(me ...interface{}) {
 	logMessage(lo, Emergency, me...)
 }
 
+// LogFn logs the message returned by fn with the given priority. The
+// function is only called if the message passes the priority check of
+// the logger so expensive messages cost nothing when the priority is
+// disabled. No allocations are made in that case.
+func (lo Logger) LogFn(pr Priority, fn func() string) {
+	logMessageFn(lo, pr, fn)
+}
+
+// TraceFn logs the message returned by fn with the Trace priority. See
+// LogFn.
+func (lo Logger) TraceFn(fn func() string) {
+	logMessageFn(lo, Trace, fn)
+}
+
+// DebugFn logs the message returned by fn with the Debug priority. See
+// LogFn.
+func (lo Logger) DebugFn(fn func() string) {
+	logMessageFn(lo, Debug, fn)
+}
+
+// InfoFn logs the message returned by fn with the Info priority. See
+// LogFn.
+func (lo Logger) InfoFn(fn func() string) {
+	logMessageFn(lo, Info, fn)
+}
+
+// NoticeFn logs the message returned by fn with the Notice priority. See
+// LogFn.
+func (lo Logger) NoticeFn(fn func() string) {
+	logMessageFn(lo, Notice, fn)
+}
+
+// WarningFn logs the message returned by fn with the Warning priority.
+// See LogFn.
+func (lo Logger) WarningFn(fn func() string) {
+	logMessageFn(lo, Warning, fn)
+}
+
+// ErrorFn logs the message returned by fn with the Error priority. See
+// LogFn.
+func (lo Logger) ErrorFn(fn func() string) {
+	logMessageFn(lo, Error, fn)
+}
+
+// CriticalFn logs the message returned by fn with the Critical priority.
+// See LogFn.
+func (lo Logger) CriticalFn(fn func() string) {
+	logMessageFn(lo, Critical, fn)
+}
+
+// AlertFn logs the message returned by fn with the Alert priority. See
+// LogFn.
+func (lo Logger) AlertFn(fn func() string) {
+	logMessageFn(lo, Alert, fn)
+}
+
+// EmergencyFn logs the message returned by fn with the Emergency
+// priority. See LogFn.
+func (lo Logger) EmergencyFn(fn func() string) {
+	logMessageFn(lo, Emergency, fn)
+}
+
 // GetLevel returns the priority level of the logger.
 func (lo Logger) GetLevel() Priority {
 	return GetLevel(lo)
@@ -266,9 +522,18 @@ func (lo Logger) SetLevel(pr Priority) (err error) {
 //
 // Message: The output message.
 //
+// Fields: The fields of the message as " key=value" pairs. Values
+// containing spaces or quotes are quoted.
+//
+// TraceID: The trace id of the message if it was logged with a trace
+// context.
+//
+// SpanID: The span id of the message if it was logged with a trace
+// context.
+//
 // The default Format is:
 //
-// "[{{.Time}} {{.Logger}} {{.Priority}}] - {{.Message}}.\n"
+// "[{{.Time}} {{.Logger}} {{.Priority}}] - {{.Message}}.{{.Fields}}\n"
 func (lo Logger) SetFormat(fo Format) {
 	SetFormat(lo, fo)
 }
@@ -287,8 +552,79 @@ func (lo Logger) SetNoColor(nc bool) {
 	SetNoColor(lo, nc)
 }
 
-// SetOutput sets the output parameter of the logger to the given
-// io.Writer. The default is os.Stderr.
+// AddFilter adds the filter to the Logger. See AddFilter for details.
+func (lo Logger) AddFilter(fi Filter) error {
+	return AddFilter(lo, fi)
+}
+
+// ClearFilters removes all filters from the Logger.
+func (lo Logger) ClearFilters() {
+	ClearFilters(lo)
+}
+
+// SetSampler sets the Sampler for records with the given priority of
+// the Logger. See SetSampler for details.
+func (lo Logger) SetSampler(pr Priority, sa Sampler) error {
+	return SetSampler(lo, pr, sa)
+}
+
+// AddMiddleware adds the middleware to the Logger. See AddMiddleware for
+// details.
+func (lo Logger) AddMiddleware(mw Middleware) error {
+	return AddMiddleware(lo, mw)
+}
+
+// ClearMiddleware removes all middleware from the Logger.
+func (lo Logger) ClearMiddleware() {
+	ClearMiddleware(lo)
+}
+
+// AddHandler adds the handler to the Logger. See AddHandler for details.
+func (lo Logger) AddHandler(ha Handler) error {
+	return AddHandler(lo, ha)
+}
+
+// RemoveHandler removes the handler from the Logger.
+func (lo Logger) RemoveHandler(ha Handler) error {
+	return RemoveHandler(lo, ha)
+}
+
+// SetRateLimiter sets the RateLimiter for the Logger. See SetRateLimiter
+// for details.
+func (lo Logger) SetRateLimiter(rl *RateLimiter) {
+	SetRateLimiter(lo, rl)
+}
+
+// SetSanitize sets the sanitize flag for the Logger. See SetSanitize for
+// details.
+func (lo Logger) SetSanitize(sa bool) {
+	SetSanitize(lo, sa)
+}
+
+// SetPropagate sets the propagate flag for the Logger. See SetPropagate
+// for details.
+func (lo Logger) SetPropagate(pa bool) {
+	SetPropagate(lo, pa)
+}
+
+// SetOutput replaces all outputs of the Logger with a single output
+// which writes to the given io.Writer. The default is os.Stderr.
+//
+// Every message is written with a single Write call. Writes are
+// serialized per writer so the writer does not have to be safe for
+// concurrent use, even if it is shared between loggers.
 func (lo Logger) SetOutput(ou io.Writer) {
 	SetOutput(lo, ou)
 }
+
+// AddOutput adds the output to the outputs of the Logger. Messages are
+// written to every output which accepts their priority.
+func (lo Logger) AddOutput(ou Output) error {
+	return AddOutput(lo, ou)
+}
+
+// RemoveOutput removes all outputs writing to the given io.Writer from
+// the Logger.
+func (lo Logger) RemoveOutput(wr io.Writer) error {
+	return RemoveOutput(lo, wr)
+}
@@ -2,7 +2,15 @@ package logger
 
 import (
 	"bytes"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 const (
@@ -38,6 +46,36 @@ func TestGetLevel(t *testing.T) {
 	n.Info(n, "Finished")
 }
 
+func TestSetLevelConcurrent(t *testing.T) {
+	l := New(namet + ".SetLevel.Concurrent")
+
+	var w sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		n := New(namet, "SetLevel", "Concurrent", strconv.Itoa(i))
+
+		w.Add(2)
+		go func() {
+			n.SetLevel(Alert)
+			w.Done()
+		}()
+		go func() {
+			New(string(n), "Child").GetLevel()
+			w.Done()
+		}()
+	}
+	w.Wait()
+
+	for i := 0; i < 50; i++ {
+		n := New(namet, "SetLevel", "Concurrent", strconv.Itoa(i))
+
+		o := n.GetLevel()
+		if o != Alert {
+			l.Critical("GOT: '", o, "', EXPECED: '", Alert, "'", ", KEY: '", n, "'")
+			t.Fail()
+		}
+	}
+}
+
 func TestSetLevelFail(t *testing.T) {
 	l := New(namet + ".SetLevel.Fail")
 
@@ -152,7 +190,545 @@ func TestGetParentOutputDifferent(t *testing.T) {
 	}
 }
 
-func TestgetParentOutputInheritance(t *testing.T) {
+func TestOutputConcurrent(t *testing.T) {
+	l := New(namet + ".Output.Concurrent")
+
+	var b bytes.Buffer
+
+	n := []Logger{
+		New(namet + ".Output.Concurrent.First"),
+		New(namet + ".Output.Concurrent.Second"),
+	}
+
+	for _, c := range n {
+		c.SetFormat("{{.Message}}\n")
+		c.SetOutput(&b)
+	}
+
+	var w sync.WaitGroup
+	for _, c := range n {
+		for i := 0; i < 10; i++ {
+			w.Add(1)
+			go func(c Logger) {
+				for j := 0; j < 100; j++ {
+					c.Notice("Test Message")
+				}
+				w.Done()
+			}(c)
+		}
+	}
+	w.Wait()
+
+	o := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(o) != 2000 {
+		l.Critical("GOT: ", len(o), " lines, EXPECTED: 2000")
+		t.Fail()
+	}
+
+	for _, v := range o {
+		if v != "Test Message" {
+			l.Critical("GOT: '", v, "', EXPECTED: 'Test Message'")
+			t.Fail()
+			return
+		}
+	}
+}
+
+// failWriter is not empty so every failWriter is a different writer.
+type failWriter struct {
+	_ byte
+}
+
+func (fa *failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestOutputError(t *testing.T) {
+	l := New(namet + ".Output.Error")
+
+	n := New(namet + ".Output.Error.Test")
+	n.SetFormat("{{.Logger}}: {{.Message}}")
+
+	f := new(failWriter)
+	n.SetOutput(f)
+
+	var e []error
+	SetErrorHandler(func(lo Logger, err error) {
+		if lo == n {
+			e = append(e, err)
+		}
+	})
+	defer SetErrorHandler(nil)
+
+	var b bytes.Buffer
+	SetFallbackOutput(&b)
+	defer SetFallbackOutput(nil)
+
+	n.Notice("Test")
+	n.Warning("Test")
+
+	if len(e) != 2 {
+		l.Critical("GOT: ", len(e), " errors, EXPECTED: 2")
+		t.Fail()
+	}
+
+	o := b.String()
+	v := string(n) + ": Test" + string(n) + ": Test"
+	if o != v {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+
+	c := FailedWrites(f)
+	if c != 2 {
+		l.Critical("GOT: ", c, " failed writes, EXPECTED: 2")
+		t.Fail()
+	}
+
+	c = FailedWrites(&b)
+	if c != 0 {
+		l.Critical("GOT: ", c, " failed writes for the fallback, EXPECTED: 0")
+		t.Fail()
+	}
+}
+
+func TestOutputErrorRecursive(t *testing.T) {
+	l := New(namet + ".Output.ErrorRecursive")
+
+	n := New(namet + ".Output.ErrorRecursive.Test")
+	n.SetOutput(new(failWriter))
+
+	var e int
+	SetErrorHandler(func(lo Logger, err error) {
+		if lo == n {
+			e++
+			n.Error("Can not write message: ", err)
+		}
+	})
+	defer SetErrorHandler(nil)
+
+	n.Notice("Test")
+	n.Warning("Test")
+
+	if e != 2 {
+		l.Critical("GOT: ", e, " errors, EXPECTED: 2")
+		t.Fail()
+	}
+}
+
+func TestOutputErrorConcurrent(t *testing.T) {
+	l := New(namet + ".Output.ErrorConcurrent")
+
+	n := New(namet + ".Output.ErrorConcurrent.Test")
+	n.SetOutput(new(failWriter))
+
+	// The handler blocks until every goroutine called it, so all calls
+	// overlap.
+	var w sync.WaitGroup
+	w.Add(4)
+
+	var e int32
+	SetErrorHandler(func(lo Logger, err error) {
+		if lo == n {
+			atomic.AddInt32(&e, 1)
+			n.Error("Can not write message: ", err)
+			w.Done()
+			w.Wait()
+		}
+	})
+	defer SetErrorHandler(nil)
+
+	d := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func() {
+			n.Notice("Test")
+			d <- struct{}{}
+		}()
+	}
+
+	for i := 0; i < 4; i++ {
+		select {
+		case <-d:
+		case <-time.After(5 * time.Second):
+			l.Critical("The error handler was not called for every goroutine")
+			t.FailNow()
+		}
+	}
+
+	if atomic.LoadInt32(&e) != 4 {
+		l.Critical("GOT: ", atomic.LoadInt32(&e), " errors, EXPECTED: 4")
+		t.Fail()
+	}
+}
+
+func TestOutputWriters(t *testing.T) {
+	l := New(namet + ".Output.Writers")
+
+	n := New(namet + ".Output.Writers.Test")
+	m := New(namet + ".Output.Writers.Other")
+
+	var a, b bytes.Buffer
+	n.SetOutput(&a)
+	m.SetOutput(&a)
+
+	o := list.GetLogger(n).Outputs[0].state
+	if o == nil || o != list.GetLogger(m).Outputs[0].state {
+		l.Critical("Outputs with the same writer do not share their state")
+		t.Fail()
+	}
+
+	n.SetOutput(&b)
+	m.SetOutput(&b)
+
+	if list.lookupWriter(&a) != nil {
+		l.Critical("Writer which is not used anymore is still known")
+		t.Fail()
+	}
+
+	if list.lookupWriter(&b) == nil {
+		l.Critical("Writer which is used is not known")
+		t.Fail()
+	}
+}
+
+func TestOutputMultiple(t *testing.T) {
+	l := New(namet + ".Output.Multiple")
+
+	n := New(namet + ".Output.Multiple.Test")
+	n.SetLevel(Debug)
+	n.SetFormat("{{.Priority}} {{.Message}}\n")
+
+	var a bytes.Buffer
+	n.SetOutput(&a)
+
+	var b bytes.Buffer
+	o := NewOutput(&b)
+	o.MinPriority = Info
+	o.MaxPriority = Warning
+	o.NoColor = true
+	n.AddOutput(o)
+
+	var c bytes.Buffer
+	o = NewOutput(&c)
+	o.Encoder = JSONEncoder{TimeFormat: "2006"}
+	o.MinPriority = Error
+	n.AddOutput(o)
+
+	n.Debug("Test")
+	n.Info("Test")
+	n.Error("Test \"1\"")
+
+	d := [][]string{
+		{"\033[0m\033[0mDebug\033[0m Test\n\033[0m\033[34mInfo\033[0m Test\n\033[1m\033[33mError\033[0m Test \"1\"\n", a.String()},
+		{"Info Test\n", b.String()},
+		{`{"time":"` + time.Now().Format("2006") + `","priority":"Error","logger":"` + string(n) + `","message":"Test \"1\""}` + "\n", c.String()},
+	}
+
+	for _, v := range d {
+		if v[0] != v[1] {
+			l.Critical("GOT: ", v[1], ", EXPECTED: ", v[0])
+			t.Fail()
+		}
+	}
+
+	e := n.RemoveOutput(&b)
+	if e != nil {
+		l.Critical("Can not remove output: ", e)
+		t.Fail()
+	}
+
+	e = n.RemoveOutput(&b)
+	if e == nil {
+		l.Critical("Removing an output twice should fail")
+		t.Fail()
+	}
+
+	n.Info("Test")
+	if b.String() != "Info Test\n" {
+		l.Critical("Removed output got message: ", b.String())
+		t.Fail()
+	}
+}
+
+func TestOutputMultipleInheritance(t *testing.T) {
+	l := New(namet + ".Output.Multiple.Inheritance")
+
+	p := New(namet + ".Output.Multiple.Inheritance.Parent")
+	p.SetFormat("{{.Message}}")
+
+	var a bytes.Buffer
+	p.SetOutput(&a)
+
+	c := New(string(p), "Child")
+	c.GetLevel()
+
+	var b bytes.Buffer
+	c.AddOutput(NewOutput(&b))
+
+	p.Notice("Parent,")
+	c.Notice("Child")
+
+	if a.String() != "Parent,Child" {
+		l.Critical("GOT: ", a.String(), ", EXPECTED: Parent,Child")
+		t.Fail()
+	}
+
+	if b.String() != "Child" {
+		l.Critical("GOT: ", b.String(), ", EXPECTED: Child")
+		t.Fail()
+	}
+
+	if len(list.GetLogger(p).Outputs) != 1 {
+		l.Critical("Adding an output to the child changed the parent")
+		t.Fail()
+	}
+}
+
+func TestJSONString(t *testing.T) {
+	l := New(namet + ".JSONString")
+
+	d := [][]string{
+		{"", `""`},
+		{"Test", `"Test"`},
+		{"\"\\\n\r\t", `"\"\\\n\r\t"`},
+		{"\x00\x1b[31m", `"\u0000\u001b[31m"`},
+		{"äö€", `"äö€"`},
+		{"\xff", "\"\ufffd\""},
+	}
+
+	for _, v := range d {
+		o := string(appendJSONString(nil, v[0]))
+		if o != v[1] {
+			l.Critical("GOT: ", o, ", EXPECTED: ", v[1])
+			t.Fail()
+		}
+	}
+}
+
+type testHandler struct {
+	priority Priority
+	records  []Record
+}
+
+func (te *testHandler) Enabled(lo Logger, pr Priority) bool {
+	return pr >= te.priority
+}
+
+func (te *testHandler) Handle(re Record) error {
+	te.records = append(te.records, re)
+
+	if re.Message == "Fail" {
+		return errors.New("handler failed")
+	}
+
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	l := New(namet + ".Handler")
+
+	n := New(namet + ".Handler.Test")
+	n.SetLevel(Debug)
+	n.SetOutput(ioutil.Discard)
+
+	h := &testHandler{priority: Info}
+	n.AddHandler(h)
+
+	c := New(string(n), "Child")
+
+	var e []error
+	SetErrorHandler(func(lo Logger, err error) {
+		e = append(e, err)
+	})
+	defer SetErrorHandler(nil)
+
+	n.Debug("Debug")
+	n.Info("Test: ", 1)
+	c.Warning("Fail")
+
+	if len(h.records) != 2 {
+		l.Critical("GOT: ", len(h.records), " records, EXPECTED: 2")
+		t.FailNow()
+	}
+
+	r := h.records[0]
+	if r.Logger != n || r.Priority != Info || r.Message != "Test: 1" {
+		l.Critical("Wrong record: ", r)
+		t.Fail()
+	}
+
+	r = h.records[1]
+	if r.Logger != c || r.Priority != Warning || r.Message != "Fail" {
+		l.Critical("Wrong record: ", r)
+		t.Fail()
+	}
+
+	if len(e) != 1 {
+		l.Critical("GOT: ", len(e), " errors, EXPECTED: 1")
+		t.Fail()
+	}
+
+	err := n.RemoveHandler(h)
+	if err != nil {
+		l.Critical("Can not remove handler: ", err)
+		t.Fail()
+	}
+
+	n.Info("Test")
+	if len(h.records) != 2 {
+		l.Critical("Removed handler got a record")
+		t.Fail()
+	}
+}
+
+func TestFilter(t *testing.T) {
+	l := New(namet + ".Filter")
+
+	n := New(namet + ".Filter.Test")
+	n.SetFormat("{{.Message}},")
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+
+	n.AddFilter(func(re Record) bool {
+		return re.Priority != Warning
+	})
+
+	e := ImportFilters(map[Logger][]string{
+		n: {"message:^GET /health"},
+	})
+	if e != nil {
+		l.Critical("Can not import filters: ", e)
+		t.Fail()
+	}
+
+	c := New(string(n), "Child")
+
+	n.Notice("GET /health")
+	n.Notice("GET /index")
+	n.Warning("Warning")
+	c.Notice("GET /health")
+	c.Notice("Child")
+
+	o := b.String()
+	v := "GET /index,Child,"
+	if o != v {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+
+	n.ClearFilters()
+	n.Warning("Warning")
+
+	o = b.String()
+	v = "GET /index,Child,Warning,"
+	if o != v {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l := New(namet + ".Middleware")
+
+	n := New(namet + ".Middleware.Test")
+	n.SetFormat("{{.Priority}} {{.Logger}} {{.Message}}{{.Fields}}\n")
+	n.SetNoColor(true)
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+
+	var c bytes.Buffer
+	o := NewOutput(&c)
+	o.Encoder = JSONEncoder{}
+	o.MinPriority = Warning
+	n.AddOutput(o)
+
+	AddGlobalMiddleware(func(re *Record) {
+		if re.Logger == n {
+			re.Fields = append(re.Fields, Field{Key: "host", Value: "test"})
+		}
+	})
+	defer ClearGlobalMiddleware()
+
+	n.AddMiddleware(AddField("version", "1.0 beta"))
+	n.AddMiddleware(func(re *Record) {
+		if re.Message == "Upgrade" {
+			re.Priority = Warning
+			re.Logger = "Renamed"
+		}
+	})
+
+	n.AddFilter(DropField("version", regexp.MustCompile("^2")))
+
+	n.Notice("Test")
+	n.Notice("Upgrade")
+
+	v := "Notice " + string(n) + " Test host=test version=\"1.0 beta\"\n" +
+		"Warning Renamed Upgrade host=test version=\"1.0 beta\"\n"
+	if b.String() != v {
+		l.Critical("GOT: ", b.String(), ", EXPECTED: ", v)
+		t.Fail()
+	}
+
+	v = `"message":"Upgrade","host":"test","version":"1.0 beta"}` + "\n"
+	if !strings.HasSuffix(c.String(), v) || strings.Count(c.String(), "\n") != 1 {
+		l.Critical("GOT: ", c.String(), ", EXPECTED suffix: ", v)
+		t.Fail()
+	}
+
+	n.ClearMiddleware()
+	n.AddMiddleware(AddField("version", "2.0"))
+	b.Reset()
+
+	n.Notice("Dropped")
+	if b.String() != "" {
+		l.Critical("GOT: ", b.String(), ", EXPECTED nothing")
+		t.Fail()
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	l := New(namet + ".ParseFilter")
+
+	r := Record{
+		Message: "Test",
+		Fields:  []Field{{Key: "path", Value: "/health"}},
+	}
+
+	m := map[string]bool{
+		"message:^Test$":      false,
+		"message:^Other$":     true,
+		"field.path:^/health": false,
+		"field.path:^/index":  true,
+		"field.other:.*":      true,
+	}
+
+	for k, v := range m {
+		f, e := ParseFilter(k)
+		if e != nil {
+			l.Critical("Can not parse filter ", k, ": ", e)
+			t.Fail()
+			continue
+		}
+
+		o := f(r)
+		if o != v {
+			l.Critical("GOT: '", o, "', EXPECED: '", v, "'", ", KEY: '", k, "'")
+			t.Fail()
+		}
+	}
+
+	for _, k := range []string{"", "message", "field.:.*", "other:.*", "message:("} {
+		_, e := ParseFilter(k)
+		if e == nil {
+			l.Critical("Parsing ", k, " should have failed")
+			t.Fail()
+		}
+	}
+}
+
+func TestGetParentOutputInheritance(t *testing.T) {
 	l := New(namet + ".GetParent.Output.Inheritance")
 
 	p := Logger("Test")
@@ -160,6 +736,8 @@ func TestgetParentOutputInheritance(t *testing.T) {
 
 	c := Logger("Test.Test")
 	c.SetLevel(Debug)
+	c.SetPropagate(true)
+	defer c.SetPropagate(false)
 	l.Info("Parent: '", getParent(c), "'")
 
 	var b bytes.Buffer
@@ -169,7 +747,7 @@ func TestgetParentOutputInheritance(t *testing.T) {
 	c.Notice("Test Child")
 
 	o := b.String()
-	v := "TestParent,Test Child"
+	v := "Test Parent,Test Child"
 
 	l.Debug("GOT: ", o, ", EXPECTED: ", v)
 	if o != v {
@@ -178,6 +756,66 @@ func TestgetParentOutputInheritance(t *testing.T) {
 	}
 }
 
+func TestPropagate(t *testing.T) {
+	l := New(namet + ".Propagate")
+
+	r := New(namet + ".Propagate.Root")
+	r.SetFormat("root: {{.Logger}} {{.Message}}\n")
+
+	var a bytes.Buffer
+	r.SetOutput(&a)
+
+	p := New(string(r), "Parent")
+	p.SetFormat("parent: {{.Logger}} {{.Message}}\n")
+
+	var b bytes.Buffer
+	p.SetOutput(&b)
+	p.AddOutput(NewOutput(&a))
+
+	c := New(string(p), "Child")
+	c.SetPropagate(true)
+	c.SetFormat("child: {{.Logger}} {{.Message}}\n")
+
+	var d bytes.Buffer
+	c.AddOutput(NewOutput(&d))
+
+	c.Notice("Test")
+
+	m := [][]string{
+		{"child: " + string(c) + " Test\n", a.String()},
+		{"child: " + string(c) + " Test\n", b.String()},
+		{"child: " + string(c) + " Test\n", d.String()},
+	}
+
+	for _, v := range m {
+		if v[0] != v[1] {
+			l.Critical("GOT: ", v[1], ", EXPECTED: ", v[0])
+			t.Fail()
+		}
+	}
+
+	a.Reset()
+	b.Reset()
+	d.Reset()
+
+	p.SetPropagate(true)
+	c.SetOutput(&d)
+	c.Notice("Test")
+
+	m = [][]string{
+		{"parent: " + string(c) + " Test\n", a.String()},
+		{"parent: " + string(c) + " Test\n", b.String()},
+		{"child: " + string(c) + " Test\n", d.String()},
+	}
+
+	for _, v := range m {
+		if v[0] != v[1] {
+			l.Critical("GOT: ", v[1], ", EXPECTED: ", v[0])
+			t.Fail()
+		}
+	}
+}
+
 func TestPrintMessage(t *testing.T) {
 	l := New(namet + ".PrintMessage")
 
@@ -201,7 +839,7 @@ func TestPrintMessage(t *testing.T) {
 		v := d[1]
 
 		var b bytes.Buffer
-		r.Output = &b
+		r.Outputs = []Output{NewOutput(&b)}
 
 		printMessage(r, Debug, k)
 		o := b.String()
@@ -235,7 +873,7 @@ func TestPrintMessageNoColor(t *testing.T) {
 		v := d[1]
 
 		var b bytes.Buffer
-		r.Output = &b
+		r.Outputs = []Output{NewOutput(&b)}
 
 		printMessage(r, Debug, k)
 		o := b.String()
@@ -274,6 +912,174 @@ func TestPrintColors(t *testing.T) {
 	l.Emergency("NoColorEmergency")
 }
 
+func TestEnabled(t *testing.T) {
+	l := New(namet + ".Enabled")
+
+	n := New(namet + ".Enabled.Test")
+	n.SetLevel(Warning)
+
+	m := map[Priority]bool{
+		Trace:     false,
+		Debug:     false,
+		Notice:    false,
+		Warning:   true,
+		Error:     true,
+		Emergency: true,
+	}
+
+	for k, v := range m {
+		o := n.Enabled(k)
+		if o != v {
+			l.Critical("GOT: '", o, "', EXPECED: '", v, "'", ", KEY: '", k, "'")
+			t.Fail()
+		}
+	}
+}
+
+func TestLogFn(t *testing.T) {
+	l := New(namet + ".LogFn")
+
+	n := New(namet + ".LogFn.Test")
+	n.SetLevel(Info)
+	n.SetFormat("{{.Message}}")
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+
+	c := 0
+	f := func() string {
+		c++
+		return "Test"
+	}
+
+	n.DebugFn(f)
+	n.InfoFn(f)
+
+	o := b.String()
+	v := "Test"
+
+	if o != v {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+
+	if c != 1 {
+		l.Critical("Function was called ", c, " times, EXPECTED: 1")
+		t.Fail()
+	}
+}
+
+func TestLogFnDisabledAllocations(t *testing.T) {
+	if raceenabled {
+		t.Skip("allocations can not be counted with the race detector")
+	}
+
+	l := New(namet + ".LogFn.Disabled.Allocations")
+
+	n := New(namet + ".LogFn.Disabled.Allocations.Test")
+	n.SetLevel(Emergency)
+
+	s := "Test"
+	a := testing.AllocsPerRun(100, func() {
+		n.DebugFn(func() string {
+			return s + s
+		})
+	})
+
+	if a != 0 {
+		l.Critical("GOT: ", a, " allocations, EXPECTED: 0")
+		t.Fail()
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	l := New(namet + ".FormatMessage")
+
+	m := new(Record)
+	m.Time = time.Date(2013, 9, 30, 20, 29, 19, 0, time.UTC)
+	m.Logger = "Test"
+	m.Priority = Debug
+	m.Message = "{{.Time}}"
+
+	d := [][]string{
+		{"", ""},
+		{"Test", "Test"},
+		{"{{.Time}}", "2013"},
+		{"{{.Logger}}{{.Priority}}", "TestDebug"},
+		{"[{{.Time}} {{.Priority}} {{.Logger}}] - {{.Message}}.", "[2013 Debug Test] - {{.Time}}."},
+		{"{{.Unknown}} {{.", "{{.Unknown}} {{."},
+	}
+
+	for _, a := range d {
+		k := Format(a[0])
+		v := a[1]
+
+		o := formatMessage(m, k, "2006", true, false)
+		if o != v {
+			l.Critical("GOT: '", o, "', EXPECED: '", v, "'", ", KEY: '", k, "'")
+			t.Fail()
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	l := New(namet + ".Sanitize")
+
+	n := New(namet + ".Sanitize.Test")
+	n.SetFormat("{{.Priority}} {{.Message}}{{.Fields}}\n")
+	n.SetSanitize(true)
+	n.AddMiddleware(AddField("user\n", "a\u2028b"))
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+
+	n.Notice("Test\n[2026-01-01T00:00:00Z Emergency root] - fake\r\x1b[31m\tEnd\u0085")
+
+	p := "\033[0m\033[32mNotice\033[0m"
+	v := p + ` Test\n[2026-01-01T00:00:00Z Emergency root] - fake\r\x1b[31m\tEnd\u0085 user\n=a\u2028b` + "\n"
+
+	o := b.String()
+	if o != v {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+}
+
+func TestLogAllocations(t *testing.T) {
+	if raceenabled {
+		t.Skip("allocations can not be counted with the race detector")
+	}
+
+	l := New(namet + ".Log.Allocations")
+
+	d := New(namet + ".Log.Allocations.Disabled")
+	d.SetLevel(Emergency)
+
+	a := testing.AllocsPerRun(100, func() {
+		d.Debug("Test")
+	})
+
+	if a != 0 {
+		l.Critical("Disabled GOT: ", a, " allocations, EXPECTED: 0")
+		t.Fail()
+	}
+
+	e := New(namet + ".Log.Allocations.Enabled")
+	e.SetLevel(Debug)
+	e.SetOutput(ioutil.Discard)
+
+	a = testing.AllocsPerRun(100, func() {
+		e.Debug("Test")
+	})
+
+	// The buffer pool can be emptied by the garbage collector during the
+	// run so allow for a refill.
+	if a > 1 {
+		l.Critical("Enabled GOT: ", a, " allocations, EXPECTED: <= 1")
+		t.Fail()
+	}
+}
+
 func TestCheckPriorityOK(t *testing.T) {
 	l := New(namet + ".CheckPriority.OK")
 
@@ -429,6 +1235,18 @@ func BenchmarkLogChildChildAllocated(b *testing.B) {
 	}
 }
 
+func BenchmarkLogFnDisabled(b *testing.B) {
+	SetLevel("BenchLogFnDisabled", Emergency)
+	l := Logger("BenchLogFnDisabled")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.DebugFn(func() string {
+			return "Test"
+		})
+	}
+}
+
 func BenchmarkGetParentRoot(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		getParent(".")
@@ -468,7 +1286,7 @@ func BenchmarkGetParentChildChildChildChildChild(b *testing.B) {
 func BenchmarkPrintMessage(b *testing.B) {
 	var a bytes.Buffer
 	l := list.GetLogger("BenchprintMessage")
-	l.Output = &a
+	l.Outputs = []Output{NewOutput(&a)}
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -479,14 +1297,67 @@ func BenchmarkPrintMessage(b *testing.B) {
 func BenchmarkFormatMessage(b *testing.B) {
 	l := list.GetLogger("BenchformatMessage")
 
-	m := new(message)
-	m.Time = "Mo 30 Sep 2013 20:29:19 CEST"
+	m := new(Record)
+	m.Time = time.Date(2013, 9, 30, 20, 29, 19, 0, time.UTC)
 	m.Logger = l.Logger
-	m.Priority = "Debug"
+	m.Priority = Debug
 	m.Message = "Test"
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		formatMessage(m, l.Format)
+		formatMessage(m, l.Format, l.TimeFormat, l.NoColor, l.Sanitize)
+	}
+}
+
+func BenchmarkAppendMessage(b *testing.B) {
+	l := list.GetLogger("BenchappendMessage")
+
+	m := new(Record)
+	m.Time = time.Date(2013, 9, 30, 20, 29, 19, 0, time.UTC)
+	m.Logger = l.Logger
+	m.Priority = Debug
+	m.Message = "Test"
+
+	var a []byte
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a = appendMessage(a[:0], m, l.Format, l.TimeFormat, l.NoColor, l.Sanitize)
+	}
+}
+
+func BenchmarkLogDisabled(b *testing.B) {
+	SetLevel("BenchLogDisabled", Emergency)
+	l := Logger("BenchLogDisabled")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Debug("Test")
+	}
+}
+
+func BenchmarkLogEnabled(b *testing.B) {
+	SetLevel("BenchLogEnabled", Debug)
+	SetOutput("BenchLogEnabled", ioutil.Discard)
+	l := Logger("BenchLogEnabled")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Debug("Test")
+	}
+}
+
+func BenchmarkLogEnabledValues(b *testing.B) {
+	SetLevel("BenchLogEnabledValues", Debug)
+	SetOutput("BenchLogEnabledValues", ioutil.Discard)
+	l := Logger("BenchLogEnabledValues")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Debug("Test: ", i)
 	}
 }
@@ -1,9 +1,13 @@
 package logger
 
 import (
+	"errors"
 	"io"
 	"os"
+	"reflect"
+	"runtime"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -19,19 +23,43 @@ type logger struct {
 	Format
 	Logger
 	Priority
-	TimeFormat string
-	NoColor    bool
-	Output     io.Writer
+	TimeFormat  string
+	NoColor     bool
+	Sanitize    bool
+	Propagate   bool
+	RateLimiter *RateLimiter
+	Outputs     []Output
+	Handlers    []Handler
+	Filters     []Filter
+	Samplers    map[Priority]Sampler
+	Middleware  []Middleware
 }
 
+// snapshot is an immutable view of the configured loggers. It must never
+// be modified after it was published, setters copy it instead.
+type snapshot map[Logger]logger
+
+// loggers holds the current snapshot of the configured loggers. Reads
+// load the snapshot without locking, writers are serialized by the mutex
+// and publish a modified copy of the snapshot.
 type loggers struct {
-	data  map[Logger]logger
-	mutex sync.RWMutex
+	data  atomic.Pointer[snapshot]
+	mutex sync.Mutex
+
+	// writers maps the writers of all outputs to their state so outputs
+	// sharing a writer share its lock. It is rebuilt when outputs change
+	// so writers which are not used anymore are forgotten.
+	writers atomic.Pointer[map[io.Writer]*writer]
+
+	errorhandler atomic.Pointer[ErrorHandler]
+	fallback     atomic.Pointer[Output]
+	middleware   atomic.Pointer[[]Middleware]
+	redactor     atomic.Pointer[Redactor]
+	override     atomic.Pointer[leveloverride]
 }
 
-func newLoggers() loggers {
+func newLoggers() *loggers {
 	l := new(loggers)
-	l.data = make(map[Logger]logger)
 
 	r := logger{
 		Format:     Format(format),
@@ -39,29 +67,143 @@ func newLoggers() loggers {
 		TimeFormat: timeformat,
 		Logger:     defroot,
 		NoColor:    false,
-		Output:     defout,
+		Outputs:    []Output{NewOutput(defout)},
 	}
 
-	l.data[defroot] = r
+	l.bindOutput(&r.Outputs[0])
+
+	d := snapshot{defroot: r}
+	l.data.Store(&d)
+
+	l.collectWriters()
 
-	return *l
+	return l
 }
 
-func (lo *loggers) SetLogger(na Logger, lg logger) {
+// setLogger publishes a new snapshot containing the given logger. The
+// mutex has to be held by the caller.
+func (lo *loggers) setLogger(na Logger, lg logger) {
+	o := *lo.data.Load()
+
+	d := make(snapshot, len(o)+1)
+	for k, v := range o {
+		d[k] = v
+	}
+	d[na] = lg
+
+	lo.data.Store(&d)
+}
+
+// update applies the given function to the logger and publishes the
+// result. Concurrent updates are serialized so no change gets lost.
+func (lo *loggers) update(na Logger, fn func(*logger)) {
 	lo.mutex.Lock()
 
-	lo.data[na] = lg
+	l := lo.GetLogger(na)
+	fn(&l)
+	lo.setLogger(na, l)
 
 	lo.mutex.Unlock()
 }
 
-func (lo *loggers) GetLogger(na Logger) logger {
-	lo.mutex.RLock()
+// updateOutputs is update for changes of the outputs. New outputs get
+// the state of their writer and writers which are not used anymore are
+// forgotten.
+func (lo *loggers) updateOutputs(na Logger, fn func(*logger)) {
+	lo.mutex.Lock()
+
+	l := lo.GetLogger(na)
+	fn(&l)
+
+	for i := range l.Outputs {
+		lo.bindOutput(&l.Outputs[i])
+	}
+
+	lo.setLogger(na, l)
+	lo.collectWriters()
+
+	lo.mutex.Unlock()
+}
+
+// bindOutput sets the state of the output to the state of its writer.
+// The mutex has to be held by the caller.
+func (lo *loggers) bindOutput(ou *Output) {
+	if ou.state != nil {
+		return
+	}
+
+	ou.state = lo.lookupWriter(ou.Writer)
+	if ou.state != nil {
+		return
+	}
+
+	ou.state = new(writer)
+
+	if !comparable(ou.Writer) {
+		return
+	}
+
+	o := lo.writers.Load()
+
+	m := make(map[io.Writer]*writer)
+	if o != nil {
+		for k, v := range *o {
+			m[k] = v
+		}
+	}
+	m[ou.Writer] = ou.state
+
+	lo.writers.Store(&m)
+}
+
+// collectWriters rebuilds the map of writers from the outputs which are
+// still in use. The mutex has to be held by the caller.
+func (lo *loggers) collectWriters() {
+	m := make(map[io.Writer]*writer)
+
+	for _, l := range *lo.data.Load() {
+		for _, o := range l.Outputs {
+			if comparable(o.Writer) {
+				m[o.Writer] = o.state
+			}
+		}
+	}
+
+	f := lo.fallback.Load()
+	if f != nil && comparable(f.Writer) {
+		m[f.Writer] = f.state
+	}
+
+	lo.writers.Store(&m)
+}
+
+// lookupWriter returns the state of the writer if it is used by an
+// output or nil.
+func (lo *loggers) lookupWriter(wr io.Writer) *writer {
+	if !comparable(wr) {
+		return nil
+	}
+
+	m := lo.writers.Load()
+	if m == nil {
+		return nil
+	}
+
+	return (*m)[wr]
+}
 
-	l, x := lo.data[na]
+func (lo *loggers) SetLogger(na Logger, lg logger) {
+	lo.mutex.Lock()
+
+	lo.setLogger(na, lg)
+
+	lo.mutex.Unlock()
+}
 
-	lo.mutex.RUnlock()
+func (lo *loggers) GetLogger(na Logger) logger {
+	d := *lo.data.Load()
 
+	l, x := d[na]
 	if !x {
 		l = lo.GetParentLogger(na)
 	}
@@ -75,8 +217,13 @@ func (lo *loggers) GetParentLogger(na Logger) logger {
 	l := lo.GetLogger(n)
 	l.Logger = na
 
-	if SaveLoggerLevels {
-		lo.SetLogger(na, l)
+	if SaveLoggerLevels && lo.mutex.TryLock() {
+		// Only save the logger if no setter changed the snapshot in the
+		// meantime, otherwise the saved copy could hide the new values.
+		if _, x := (*lo.data.Load())[na]; !x {
+			lo.setLogger(na, l)
+		}
+		lo.mutex.Unlock()
 	}
 
 	return l
@@ -94,43 +241,341 @@ func (lo *loggers) SetLevel(na Logger, pr Priority) (err error) {
 		return
 	}
 
-	l := lo.GetLogger(na)
-	l.Priority = pr
-	lo.SetLogger(na, l)
+	lo.update(na, func(l *logger) {
+		l.Priority = pr
+	})
 
 	return
 }
 
 func (lo *loggers) SetFormat(na Logger, fo Format) (err error) {
 	//TODO: Validate Format
-	l := lo.GetLogger(na)
-	l.Format = fo
-	lo.SetLogger(na, l)
+	lo.update(na, func(l *logger) {
+		l.Format = fo
+	})
 
 	return
 }
 
 func (lo *loggers) SetTimeFormat(na Logger, fo string) (err error) {
 	//TODO: Validate TimeFormat
-	l := lo.GetLogger(na)
-	l.TimeFormat = fo
-	lo.SetLogger(na, l)
+	lo.update(na, func(l *logger) {
+		l.TimeFormat = fo
+	})
 
 	return
 }
 
 func (lo *loggers) SetNoColor(na Logger, nc bool) {
-	l := lo.GetLogger(na)
-	l.NoColor = nc
-	lo.SetLogger(na, l)
+	lo.update(na, func(l *logger) {
+		l.NoColor = nc
+	})
+
+	return
+}
+
+func (lo *loggers) SetRateLimiter(na Logger, rl *RateLimiter) {
+	lo.update(na, func(l *logger) {
+		l.RateLimiter = rl
+	})
+
+	return
+}
+
+func (lo *loggers) SetSanitize(na Logger, sa bool) {
+	lo.update(na, func(l *logger) {
+		l.Sanitize = sa
+	})
+
+	return
+}
+
+func (lo *loggers) SetPropagate(na Logger, pa bool) {
+	lo.update(na, func(l *logger) {
+		l.Propagate = pa
+	})
 
 	return
 }
 
 func (lo *loggers) SetOutput(na Logger, ou io.Writer) (err error) {
-	l := lo.GetLogger(na)
-	l.Output = ou
-	lo.SetLogger(na, l)
+	o := NewOutput(ou)
+
+	err = o.check()
+	if err != nil {
+		return
+	}
+
+	lo.updateOutputs(na, func(l *logger) {
+		l.Outputs = []Output{o}
+	})
+
+	return
+}
+
+func (lo *loggers) AddOutput(na Logger, ou Output) (err error) {
+	err = ou.check()
+	if err != nil {
+		return
+	}
+
+	lo.updateOutputs(na, func(l *logger) {
+		// The slice is shared with the parent and older snapshots so it
+		// has to be copied before appending.
+		l.Outputs = append(l.Outputs[:len(l.Outputs):len(l.Outputs)], ou)
+	})
+
+	return
+}
+
+func (lo *loggers) RemoveOutput(na Logger, wr io.Writer) (err error) {
+	lo.updateOutputs(na, func(l *logger) {
+		o := make([]Output, 0, len(l.Outputs))
+		for _, v := range l.Outputs {
+			if !same(v.Writer, wr) {
+				o = append(o, v)
+			}
+		}
+
+		if len(o) == len(l.Outputs) {
+			err = errors.New("the logger has no output with the given writer")
+			return
+		}
+
+		l.Outputs = o
+	})
 
 	return
 }
+
+func (lo *loggers) AddHandler(na Logger, ha Handler) (err error) {
+	if ha == nil {
+		err = errors.New("the handler is nil")
+		return
+	}
+
+	lo.update(na, func(l *logger) {
+		// The slice is shared with the parent and older snapshots so it
+		// has to be copied before appending.
+		l.Handlers = append(l.Handlers[:len(l.Handlers):len(l.Handlers)], ha)
+	})
+
+	return
+}
+
+func (lo *loggers) RemoveHandler(na Logger, ha Handler) (err error) {
+	lo.update(na, func(l *logger) {
+		h := make([]Handler, 0, len(l.Handlers))
+		for _, v := range l.Handlers {
+			if !same(v, ha) {
+				h = append(h, v)
+			}
+		}
+
+		if len(h) == len(l.Handlers) {
+			err = errors.New("the logger does not have the given handler")
+			return
+		}
+
+		l.Handlers = h
+	})
+
+	return
+}
+
+func (lo *loggers) AddFilter(na Logger, fi Filter) (err error) {
+	if fi == nil {
+		err = errors.New("the filter is nil")
+		return
+	}
+
+	lo.update(na, func(l *logger) {
+		// The slice is shared with the parent and older snapshots so it
+		// has to be copied before appending.
+		l.Filters = append(l.Filters[:len(l.Filters):len(l.Filters)], fi)
+	})
+
+	return
+}
+
+func (lo *loggers) ClearFilters(na Logger) {
+	lo.update(na, func(l *logger) {
+		l.Filters = nil
+	})
+
+	return
+}
+
+func (lo *loggers) AddMiddleware(na Logger, mw Middleware) (err error) {
+	if mw == nil {
+		err = errors.New("the middleware is nil")
+		return
+	}
+
+	lo.update(na, func(l *logger) {
+		// The slice is shared with the parent and older snapshots so it
+		// has to be copied before appending.
+		l.Middleware = append(l.Middleware[:len(l.Middleware):len(l.Middleware)], mw)
+	})
+
+	return
+}
+
+func (lo *loggers) ClearMiddleware(na Logger) {
+	lo.update(na, func(l *logger) {
+		l.Middleware = nil
+	})
+
+	return
+}
+
+func (lo *loggers) AddGlobalMiddleware(mw Middleware) (err error) {
+	if mw == nil {
+		err = errors.New("the middleware is nil")
+		return
+	}
+
+	lo.mutex.Lock()
+
+	var m []Middleware
+	o := lo.middleware.Load()
+	if o != nil {
+		m = *o
+	}
+
+	m = append(m[:len(m):len(m)], mw)
+	lo.middleware.Store(&m)
+
+	lo.mutex.Unlock()
+
+	return
+}
+
+func (lo *loggers) ClearGlobalMiddleware() {
+	lo.mutex.Lock()
+	lo.middleware.Store(nil)
+	lo.mutex.Unlock()
+}
+
+// runMiddleware runs the global middleware and the middleware of the
+// logger in that order on the record. Secrets are redacted afterwards
+// so fields added by middleware are redacted too.
+func (lo *loggers) runMiddleware(lg logger, re *Record) {
+	m := lo.middleware.Load()
+	if m != nil {
+		runMiddleware(*m, re)
+	}
+
+	runMiddleware(lg.Middleware, re)
+
+	r := lo.redactor.Load()
+	if r != nil {
+		r.redactRecord(re)
+	}
+}
+
+func (lo *loggers) SetRedactor(rd *Redactor) {
+	lo.redactor.Store(rd)
+}
+
+func (lo *loggers) SetSampler(na Logger, pr Priority, sa Sampler) (err error) {
+	err = checkPriority(pr)
+	if err != nil {
+		return
+	}
+
+	lo.update(na, func(l *logger) {
+		// The map is shared with the parent and older snapshots so it
+		// has to be copied before it is changed.
+		s := make(map[Priority]Sampler, len(l.Samplers)+1)
+		for k, v := range l.Samplers {
+			s[k] = v
+		}
+
+		if sa == nil {
+			delete(s, pr)
+		} else {
+			s[pr] = sa
+		}
+
+		l.Samplers = s
+	})
+
+	return
+}
+
+func (lo *loggers) SetErrorHandler(eh ErrorHandler) {
+	if eh == nil {
+		lo.errorhandler.Store(nil)
+		return
+	}
+
+	lo.errorhandler.Store(&eh)
+}
+
+func (lo *loggers) SetFallbackOutput(ou io.Writer) {
+	lo.mutex.Lock()
+	defer lo.mutex.Unlock()
+
+	if ou == nil {
+		lo.fallback.Store(nil)
+		lo.collectWriters()
+		return
+	}
+
+	o := NewOutput(ou)
+	lo.bindOutput(&o)
+
+	lo.fallback.Store(&o)
+	lo.collectWriters()
+}
+
+// handleError is called when the line could not be written to the output
+// of the logger or a handler of the logger failed. It calls the error
+// handler and writes the line to the fallback output if they are set.
+// Errors of handlers have no line. The error handler is not called for
+// errors of messages it logs itself so it can log the error without
+// recursing.
+func (lo *loggers) handleError(lg logger, li []byte, err error) {
+	h := lo.errorhandler.Load()
+	if h != nil && !inErrorHandler() {
+		callErrorHandler(*h, lg.Logger, err)
+	}
+
+	if li == nil {
+		return
+	}
+
+	f := lo.fallback.Load()
+	if f != nil {
+		writeOutput(f.state, f.Writer, li)
+	}
+}
+
+// callErrorHandler calls the error handler. It must not be inlined so
+// inErrorHandler can find it on the stack.
+//
+//go:noinline
+func callErrorHandler(eh ErrorHandler, lo Logger, err error) {
+	eh(lo, err)
+}
+
+var errorhandlerfunc = runtime.FuncForPC(reflect.ValueOf(callErrorHandler).Pointer()).Name()
+
+// inErrorHandler returns true if the calling goroutine is running the
+// error handler. Errors of other goroutines still reach the handler.
+func inErrorHandler() bool {
+	var pc [256]uintptr
+
+	f := runtime.CallersFrames(pc[:runtime.Callers(2, pc[:])])
+	for {
+		r, m := f.Next()
+		if r.Function == errorhandlerfunc {
+			return true
+		}
+
+		if !m {
+			return false
+		}
+	}
+}
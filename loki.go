@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// DefaultLokiURL is the default endpoint of the LokiHandler.
+const DefaultLokiURL = "http://localhost:3100/loki/api/v1/push"
+
+// LokiHandler is a Handler which pushes records to Grafana Loki with the
+// JSON push API. Every record gets the labels "logger" and "level" with
+// the name of the logger and the priority of the record in addition to
+// the static Labels. The lines are formatted with the Format and
+// TimeFormat of the logger, colors are never used. Records are batched in
+// the background, see Batcher. Header is sent with every request, for
+// example the X-Scope-OrgID of the tenant.
+type LokiHandler struct {
+	Batcher
+
+	URL         string
+	Client      *http.Client
+	Header      http.Header
+	Labels      map[string]string
+	MinPriority Priority
+}
+
+// NewLokiHandler returns a LokiHandler which pushes records to the given
+// URL. An empty URL uses DefaultLokiURL. Requests time out after
+// DefaultHTTPTimeout.
+func NewLokiHandler(url string) *LokiHandler {
+	if url == "" {
+		url = DefaultLokiURL
+	}
+
+	h := &LokiHandler{
+		URL:    url,
+		Client: &http.Client{Timeout: DefaultHTTPTimeout},
+		Header: make(http.Header),
+	}
+	h.Batcher = newBatcher(h.send)
+
+	return h
+}
+
+// Enabled implements the Handler interface.
+func (lk *LokiHandler) Enabled(lo Logger, pr Priority) bool {
+	return pr >= lk.MinPriority
+}
+
+// Handle implements the Handler interface. The record is formatted and
+// queued to be sent in the background.
+func (lk *LokiHandler) Handle(re Record) error {
+	l := list.GetLogger(re.Logger)
+
+	b := getBuffer()
+	b.line = appendMessage(b.line[:0], &re, l.Format, l.TimeFormat, true, l.Sanitize)
+	re.Message = string(bytes.TrimSuffix(b.line, []byte("\n")))
+	re.Fields = nil
+	putBuffer(b)
+
+	return lk.enqueue(re)
+}
+
+type lokiStream struct {
+	logger   Logger
+	priority Priority
+	values   []byte
+}
+
+// encodeLoki encodes the records as a push request with one stream for
+// every logger and priority.
+func encodeLoki(re []Record, la map[string]string) []byte {
+	var s []*lokiStream
+	i := make(map[Logger]map[Priority]*lokiStream)
+
+	for _, r := range re {
+		p, x := i[r.Logger]
+		if !x {
+			p = make(map[Priority]*lokiStream)
+			i[r.Logger] = p
+		}
+
+		t, x := p[r.Priority]
+		if !x {
+			t = &lokiStream{logger: r.Logger, priority: r.Priority}
+			p[r.Priority] = t
+			s = append(s, t)
+		} else {
+			t.values = append(t.values, ',')
+		}
+
+		t.values = append(t.values, `["`...)
+		t.values = strconv.AppendInt(t.values, r.Time.UnixNano(), 10)
+		t.values = append(t.values, `",`...)
+		t.values = appendJSONString(t.values, r.Message)
+		t.values = append(t.values, ']')
+	}
+
+	k := make([]string, 0, len(la))
+	for l := range la {
+		if l != "logger" && l != "level" {
+			k = append(k, l)
+		}
+	}
+	sort.Strings(k)
+
+	b := []byte(`{"streams":[`)
+
+	for n, t := range s {
+		if n > 0 {
+			b = append(b, ',')
+		}
+
+		b = append(b, `{"stream":{"logger":`...)
+		b = appendJSONString(b, string(t.logger))
+		b = append(b, `,"level":`...)
+		b = appendJSONString(b, priorities[t.priority])
+
+		for _, l := range k {
+			b = append(b, ',')
+			b = appendJSONString(b, l)
+			b = append(b, ':')
+			b = appendJSONString(b, la[l])
+		}
+
+		b = append(b, `},"values":[`...)
+		b = append(b, t.values...)
+		b = append(b, "]}"...)
+	}
+
+	return append(b, "]}"...)
+}
+
+func (lk *LokiHandler) send(re []Record) error {
+	_, err := postRequest(lk.Client, lk.URL, lk.Header, "application/json", encodeLoki(re, lk.Labels))
+	return err
+}
@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type lokiRequest struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func TestLokiHandler(t *testing.T) {
+	l := New(namet + ".LokiHandler")
+
+	var mu sync.Mutex
+	var q []lokiRequest
+	var i int32
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&i, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if r.URL.Path != "/loki/api/v1/push" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Scope-OrgID") != "test" {
+			http.Error(w, "wrong request", http.StatusBadRequest)
+			return
+		}
+
+		b, _ := ioutil.ReadAll(r.Body)
+
+		var o lokiRequest
+		if err := json.Unmarshal(b, &o); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		q = append(q, o)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	h := NewLokiHandler(s.URL + "/loki/api/v1/push")
+	h.Labels = map[string]string{"job": "test", "env": "dev"}
+	h.Header.Set("X-Scope-OrgID", "test")
+	h.Backoff = time.Millisecond
+
+	n := New(namet + ".LokiHandler.Test")
+	n.SetLevel(Info)
+	n.SetNoColor(true)
+	n.SetFormat("{{.Priority}} {{.Message}}{{.Fields}}\n")
+	n.SetOutput(ioutil.Discard)
+	n.AddHandler(h)
+
+	n.Info("Test1")
+	n.Warning("Test2")
+	n.Info("Test3")
+
+	err := h.Close()
+	if err != nil {
+		l.Critical("Can not close handler: ", err)
+		t.FailNow()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if atomic.LoadInt32(&i) != 2 || len(q) != 1 || len(q[0].Streams) != 2 {
+		l.Critical("Wrong requests: ", atomic.LoadInt32(&i), " ", q)
+		t.FailNow()
+	}
+
+	v := []map[string]string{
+		{"logger": string(n), "level": "Info", "job": "test", "env": "dev"},
+		{"logger": string(n), "level": "Warning", "job": "test", "env": "dev"},
+	}
+
+	e := [][]string{
+		{"Info Test1", "Info Test3"},
+		{"Warning Test2"},
+	}
+
+	for k, o := range q[0].Streams {
+		if !reflect.DeepEqual(o.Stream, v[k]) {
+			l.Critical("GOT: ", o.Stream, ", EXPECTED: ", v[k])
+			t.Fail()
+		}
+
+		var m []string
+		for _, a := range o.Values {
+			if a[0] == "" || a[0] == "0" {
+				l.Critical("Missing time: ", a)
+				t.Fail()
+			}
+
+			m = append(m, a[1])
+		}
+
+		if !reflect.DeepEqual(m, e[k]) {
+			l.Critical("GOT: ", m, ", EXPECTED: ", e[k])
+			t.Fail()
+		}
+	}
+}
+
+func TestLokiHandlerBatch(t *testing.T) {
+	l := New(namet + ".LokiHandlerBatch")
+
+	c := make(chan int, 10)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+
+		var o lokiRequest
+		json.Unmarshal(b, &o)
+
+		n := 0
+		for _, v := range o.Streams {
+			n += len(v.Values)
+		}
+
+		c <- n
+	}))
+	defer s.Close()
+
+	h := NewLokiHandler(s.URL)
+	h.BatchSize = 2
+	h.BatchAge = 50 * time.Millisecond
+	defer h.Close()
+
+	for _, v := range []string{"Test1", "Test2", "Test3"} {
+		h.Handle(Record{Time: time.Now(), Logger: namet + ".LokiHandlerBatch.Test", Priority: Info, Message: v})
+	}
+
+	for _, v := range []int{2, 1} {
+		select {
+		case o := <-c:
+			if o != v {
+				l.Critical("GOT: ", o, ", EXPECTED: ", v)
+				t.Fail()
+			}
+		case <-time.After(5 * time.Second):
+			l.Critical("No batch received")
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"runtime/debug"
+)
+
+// FieldStackTrace is the key of the field added by AddStackTrace.
+const FieldStackTrace = "stack_trace"
+
+// Middleware can modify a record after it passed the priority check of
+// its logger and before it is filtered and encoded. It can for example
+// add fields, rename the logger or change the priority of the record.
+type Middleware func(re *Record)
+
+// AddField returns a Middleware which adds the field with the given key
+// and value to every record.
+func AddField(key, value string) Middleware {
+	return func(re *Record) {
+		re.Fields = append(re.Fields[:len(re.Fields):len(re.Fields)], Field{Key: key, Value: value})
+	}
+}
+
+// AddStackTrace returns a Middleware which adds the stack trace of the
+// calling goroutine as the field FieldStackTrace to every record with the
+// given priority or higher.
+func AddStackTrace(pr Priority) Middleware {
+	return func(re *Record) {
+		if re.Priority < pr {
+			return
+		}
+
+		re.Fields = append(re.Fields[:len(re.Fields):len(re.Fields)], Field{Key: FieldStackTrace, Value: string(debug.Stack())})
+	}
+}
+
+func runMiddleware(mw []Middleware, re *Record) {
+	for _, m := range mw {
+		m(re)
+	}
+}
@@ -0,0 +1,247 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// The MessagePack encoding is only implemented as far as the forward
+// protocol needs it.
+
+func appendMsgpackString(b []byte, s string) []byte {
+	n := len(s)
+
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xdb, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+
+	return append(b, s...)
+}
+
+func appendMsgpackBin(b []byte, bi []byte) []byte {
+	n := len(bi)
+
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xc5, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xc6, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+
+	return append(b, bi...)
+}
+
+func appendMsgpackArray(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xdc, byte(n>>8), byte(n))
+	default:
+		return append(b, 0xdd, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+func appendMsgpackMap(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xde, byte(n>>8), byte(n))
+	default:
+		return append(b, 0xdf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+func appendMsgpackUint(b []byte, u uint64) []byte {
+	switch {
+	case u < 128:
+		return append(b, byte(u))
+	case u <= math.MaxUint8:
+		return append(b, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return append(b, 0xcd, byte(u>>8), byte(u))
+	case u <= math.MaxUint32:
+		return append(b, 0xce, byte(u>>24), byte(u>>16), byte(u>>8), byte(u))
+	default:
+		b = append(b, 0xcf)
+		return binary.BigEndian.AppendUint64(b, u)
+	}
+}
+
+// appendMsgpackEventTime appends the time as the EventTime extension of
+// the forward protocol.
+func appendMsgpackEventTime(b []byte, ti time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = binary.BigEndian.AppendUint32(b, uint32(ti.Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(ti.Nanosecond()))
+}
+
+// readMsgpack reads one value. Maps are returned as map[string]interface{}
+// with their keys converted to strings, EventTime extensions as time.Time
+// and binary data as []byte.
+func readMsgpack(r *bufio.Reader) (va interface{}, err error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return
+	}
+
+	switch {
+	case c <= 0x7f:
+		return uint64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return readMsgpackString(r, int(c&0x1f))
+	case c&0xf0 == 0x90:
+		return readMsgpackArray(r, int(c&0x0f))
+	case c&0xf0 == 0x80:
+		return readMsgpackMap(r, int(c&0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readMsgpackLength(r, c-0xc4)
+		if err != nil {
+			return nil, err
+		}
+
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return b, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := readMsgpackUint(r, 1<<(c-0xcc))
+		return n, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n, err := readMsgpackUint(r, 1<<(c-0xd0))
+		switch c {
+		case 0xd0:
+			return int64(int8(n)), err
+		case 0xd1:
+			return int64(int16(n)), err
+		case 0xd2:
+			return int64(int32(n)), err
+		}
+		return int64(n), err
+	case 0xd7:
+		var b [9]byte
+		_, err = io.ReadFull(r, b[:])
+		if err != nil {
+			return
+		}
+
+		if b[0] != 0x00 {
+			return nil, errors.New("can not read msgpack: unknown extension " + strconv.Itoa(int(b[0])))
+		}
+
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:5])), int64(binary.BigEndian.Uint32(b[5:9]))), nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackLength(r, c-0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, n)
+	case 0xdc, 0xdd:
+		n, err := readMsgpackLength(r, c-0xdc+1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(r, n)
+	case 0xde, 0xdf:
+		n, err := readMsgpackLength(r, c-0xde+1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(r, n)
+	}
+
+	return nil, errors.New("can not read msgpack: unknown type " + strconv.Itoa(int(c)))
+}
+
+// readMsgpackLength reads a length of 1, 2 or 4 bytes for si 0, 1 or 2.
+func readMsgpackLength(r *bufio.Reader, si byte) (int, error) {
+	n, err := readMsgpackUint(r, 1<<si)
+	return int(n), err
+}
+
+func readMsgpackUint(r *bufio.Reader, by int) (u uint64, err error) {
+	var b [8]byte
+
+	_, err = io.ReadFull(r, b[:by])
+	if err != nil {
+		return
+	}
+
+	for _, c := range b[:by] {
+		u = u<<8 | uint64(c)
+	}
+
+	return
+}
+
+func readMsgpackString(r *bufio.Reader, n int) (string, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+
+	return string(b), err
+}
+
+func readMsgpackArray(r *bufio.Reader, n int) ([]interface{}, error) {
+	a := make([]interface{}, n)
+
+	for i := range a {
+		v, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+
+		a[i] = v
+	}
+
+	return a, nil
+}
+
+func readMsgpackMap(r *bufio.Reader, n int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, n)
+
+	for i := 0; i < n; i++ {
+		k, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := readMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+
+		s, ok := k.(string)
+		if !ok {
+			return nil, errors.New("can not read msgpack: map key is not a string")
+		}
+
+		m[s] = v
+	}
+
+	return m, nil
+}
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultOTLPURL is the default endpoint of the OTLP/HTTP logs exporter.
+const DefaultOTLPURL = "http://localhost:4318/v1/logs"
+
+// DefaultHTTPTimeout is the timeout of the HTTP clients of the exporting
+// handlers.
+const DefaultHTTPTimeout = 10 * time.Second
+
+// otlpseverities maps the priorities to the OpenTelemetry severity
+// numbers.
+var otlpseverities = map[Priority]int{
+	Trace:     1,
+	Debug:     5,
+	Info:      9,
+	Notice:    10,
+	Warning:   13,
+	Error:     17,
+	Critical:  18,
+	Alert:     19,
+	Emergency: 21,
+}
+
+// OTLPHandler is a Handler which exports records to an OpenTelemetry
+// collector with OTLP/HTTP using the JSON encoding. Records are batched
+// in the background, see Batcher. The name of the logger is used as the
+// instrumentation scope, the fields become attributes and the trace
+// context is kept.
+type OTLPHandler struct {
+	Batcher
+
+	URL         string
+	Client      *http.Client
+	Header      http.Header
+	Resource    []Field
+	MinPriority Priority
+}
+
+// NewOTLPHandler returns an OTLPHandler which sends records to the given
+// URL. An empty URL uses DefaultOTLPURL. Requests time out after
+// DefaultHTTPTimeout.
+func NewOTLPHandler(url string) *OTLPHandler {
+	if url == "" {
+		url = DefaultOTLPURL
+	}
+
+	h := &OTLPHandler{
+		URL:    url,
+		Client: &http.Client{Timeout: DefaultHTTPTimeout},
+		Header: make(http.Header),
+	}
+	h.Batcher = newBatcher(h.send)
+
+	return h
+}
+
+// Enabled implements the Handler interface.
+func (ot *OTLPHandler) Enabled(lo Logger, pr Priority) bool {
+	return pr >= ot.MinPriority
+}
+
+// Handle implements the Handler interface. The record is queued and
+// sent in the background.
+func (ot *OTLPHandler) Handle(re Record) error {
+	return ot.enqueue(re)
+}
+
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string          `json:"timeUnixNano"`
+	ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+	SeverityNumber       int             `json:"severityNumber"`
+	SeverityText         string          `json:"severityText"`
+	Body                 otlpValue       `json:"body"`
+	Attributes           []otlpAttribute `json:"attributes,omitempty"`
+	TraceID              string          `json:"traceId,omitempty"`
+	SpanID               string          `json:"spanId,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func otlpAttributes(fi []Field) []otlpAttribute {
+	if len(fi) == 0 {
+		return nil
+	}
+
+	a := make([]otlpAttribute, len(fi))
+	for i, f := range fi {
+		a[i] = otlpAttribute{Key: f.Key, Value: otlpValue{StringValue: f.Value}}
+	}
+
+	return a
+}
+
+// encodeOTLP encodes the records as an ExportLogsServiceRequest. Records
+// are grouped by their logger into one scope per logger.
+func encodeOTLP(re []Record, rs []Field) ([]byte, error) {
+	o := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	var s []otlpScopeLogs
+	i := make(map[Logger]int)
+
+	for _, r := range re {
+		n, x := i[r.Logger]
+		if !x {
+			n = len(s)
+			i[r.Logger] = n
+			s = append(s, otlpScopeLogs{Scope: otlpScope{Name: string(r.Logger)}})
+		}
+
+		s[n].LogRecords = append(s[n].LogRecords, otlpLogRecord{
+			TimeUnixNano:         strconv.FormatInt(r.Time.UnixNano(), 10),
+			ObservedTimeUnixNano: o,
+			SeverityNumber:       otlpseverities[r.Priority],
+			SeverityText:         priorities[r.Priority],
+			Body:                 otlpValue{StringValue: r.Message},
+			Attributes:           otlpAttributes(r.Fields),
+			TraceID:              r.TraceID,
+			SpanID:               r.SpanID,
+		})
+	}
+
+	q := otlpRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource:  otlpResource{Attributes: otlpAttributes(rs)},
+			ScopeLogs: s,
+		}},
+	}
+
+	return json.Marshal(q)
+}
+
+func (ot *OTLPHandler) send(re []Record) error {
+	b, err := encodeOTLP(re, ot.Resource)
+	if err != nil {
+		return permanentError{err}
+	}
+
+	_, err = postRequest(ot.Client, ot.URL, ot.Header, "application/json", b)
+	return err
+}
+
+// postRequest posts the body to the URL and returns the body of the
+// response. Responses with a 5xx or 429 status and network errors can be
+// retried, all other failed responses are permanent errors.
+func postRequest(cl *http.Client, ur string, he http.Header, ct string, bo []byte) ([]byte, error) {
+	q, err := http.NewRequest("POST", ur, bytes.NewReader(bo))
+	if err != nil {
+		return nil, permanentError{err}
+	}
+
+	for k, v := range he {
+		q.Header[k] = v
+	}
+	q.Header.Set("Content-Type", ct)
+
+	r, err := cl.Do(q)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode >= 200 && r.StatusCode < 300 {
+		return ioutil.ReadAll(r.Body)
+	}
+
+	m, _ := ioutil.ReadAll(io.LimitReader(r.Body, 1024))
+
+	e := errors.New("can not send records: " + r.Status + " " + string(bytes.TrimSpace(m)))
+	if r.StatusCode >= 500 || r.StatusCode == http.StatusTooManyRequests {
+		return nil, e
+	}
+
+	return nil, permanentError{e}
+}
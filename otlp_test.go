@@ -0,0 +1,247 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOTLPHandler(t *testing.T) {
+	l := New(namet + ".OTLPHandler")
+
+	var mu sync.Mutex
+	var q []otlpRequest
+	var c string
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+
+		var o otlpRequest
+		if err := json.Unmarshal(b, &o); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		q = append(q, o)
+		c = r.Header.Get("Content-Type")
+		mu.Unlock()
+	}))
+	defer s.Close()
+
+	h := NewOTLPHandler(s.URL)
+	h.Resource = []Field{{"service.name", "test"}}
+
+	n := New(namet + ".OTLPHandler.Test")
+	n.SetLevel(Info)
+	n.SetOutput(ioutil.Discard)
+	n.AddHandler(h)
+
+	ctx := WithTraceContext(WithUser(context.Background(), "alice"), TraceContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+	})
+
+	n.WarningCtx(ctx, "Test")
+
+	err := h.Close()
+	if err != nil {
+		l.Critical("Can not close handler: ", err)
+		t.FailNow()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(q) != 1 || len(q[0].ResourceLogs) != 1 || len(q[0].ResourceLogs[0].ScopeLogs) != 1 {
+		l.Critical("Wrong number of requests: ", q)
+		t.FailNow()
+	}
+
+	if c != "application/json" {
+		l.Critical("GOT: ", c, ", EXPECTED: application/json")
+		t.Fail()
+	}
+
+	a := q[0].ResourceLogs[0].Resource.Attributes
+	if len(a) != 1 || a[0].Key != "service.name" || a[0].Value.StringValue != "test" {
+		l.Critical("Wrong resource attributes: ", a)
+		t.Fail()
+	}
+
+	o := q[0].ResourceLogs[0].ScopeLogs[0]
+	if o.Scope.Name != string(n) || len(o.LogRecords) != 1 {
+		l.Critical("Wrong scope logs: ", o)
+		t.FailNow()
+	}
+
+	r := o.LogRecords[0]
+	if r.SeverityNumber != 13 || r.SeverityText != "Warning" || r.Body.StringValue != "Test" {
+		l.Critical("Wrong log record: ", r)
+		t.Fail()
+	}
+
+	if r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || r.SpanID != "00f067aa0ba902b7" {
+		l.Critical("Wrong trace context: ", r)
+		t.Fail()
+	}
+
+	if len(r.Attributes) != 1 || r.Attributes[0].Key != "user" || r.Attributes[0].Value.StringValue != "alice" {
+		l.Critical("Wrong attributes: ", r.Attributes)
+		t.Fail()
+	}
+
+	if r.TimeUnixNano == "" || r.TimeUnixNano == "0" {
+		l.Critical("Missing time: ", r)
+		t.Fail()
+	}
+}
+
+func TestOTLPHandlerRetry(t *testing.T) {
+	l := New(namet + ".OTLPHandlerRetry")
+
+	var i int32
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&i, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+
+	h := NewOTLPHandler(s.URL)
+	h.Backoff = time.Millisecond
+
+	h.Handle(Record{Logger: namet + ".OTLPHandlerRetry.Test", Priority: Info, Message: "Test"})
+
+	err := h.Flush()
+	if err != nil {
+		l.Critical("Can not flush handler: ", err)
+		t.Fail()
+	}
+
+	if atomic.LoadInt32(&i) != 3 {
+		l.Critical("GOT: ", atomic.LoadInt32(&i), ", EXPECTED: ", 3)
+		t.Fail()
+	}
+
+	h.Close()
+}
+
+func TestOTLPHandlerPermanent(t *testing.T) {
+	l := New(namet + ".OTLPHandlerPermanent")
+
+	var i int32
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&i, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer s.Close()
+
+	var e int32
+	SetErrorHandler(func(lo Logger, err error) {
+		atomic.AddInt32(&e, 1)
+	})
+	defer SetErrorHandler(nil)
+
+	h := NewOTLPHandler(s.URL)
+	h.Backoff = time.Millisecond
+
+	h.Handle(Record{Logger: namet + ".OTLPHandlerPermanent.Test", Priority: Info, Message: "Test"})
+
+	err := h.Flush()
+	if err == nil {
+		l.Critical("Flush should have failed")
+		t.Fail()
+	}
+
+	if atomic.LoadInt32(&i) != 1 || atomic.LoadInt32(&e) != 1 {
+		l.Critical("GOT: ", atomic.LoadInt32(&i), " requests and ", atomic.LoadInt32(&e), " errors, EXPECTED: 1 and 1")
+		t.Fail()
+	}
+
+	h.Close()
+}
+
+func TestOTLPHandlerQueue(t *testing.T) {
+	l := New(namet + ".OTLPHandlerQueue")
+
+	var i int32
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&i, 1)
+	}))
+	defer s.Close()
+
+	h := NewOTLPHandler(s.URL)
+	h.QueueSize = 2
+	h.BatchAge = time.Hour
+
+	for k := 0; k < 5; k++ {
+		h.Handle(Record{Logger: namet + ".OTLPHandlerQueue.Test", Priority: Info, Message: "Test"})
+	}
+
+	if h.Dropped() != 3 {
+		l.Critical("GOT: ", h.Dropped(), ", EXPECTED: ", 3)
+		t.Fail()
+	}
+
+	h.Close()
+
+	if atomic.LoadInt32(&i) != 1 {
+		l.Critical("GOT: ", atomic.LoadInt32(&i), ", EXPECTED: ", 1)
+		t.Fail()
+	}
+
+	err := h.Handle(Record{Logger: namet + ".OTLPHandlerQueue.Test", Priority: Info, Message: "Test"})
+	if err == nil {
+		l.Critical("Handle should fail after Close")
+		t.Fail()
+	}
+}
+
+func TestOTLPHandlerCloseTimeout(t *testing.T) {
+	l := New(namet + ".OTLPHandlerCloseTimeout")
+
+	// The server accepts the request but never replies.
+	d := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-d
+	}))
+	defer s.Close()
+	defer close(d)
+
+	h := NewOTLPHandler(s.URL)
+	if h.Client.Timeout != DefaultHTTPTimeout {
+		l.Critical("GOT: ", h.Client.Timeout, ", EXPECTED: ", DefaultHTTPTimeout)
+		t.Fail()
+	}
+
+	h.Client = &http.Client{}
+	h.CloseTimeout = 50 * time.Millisecond
+
+	h.Handle(Record{Logger: namet + ".OTLPHandlerCloseTimeout.Test", Priority: Info, Message: "Test"})
+
+	c := make(chan error, 1)
+	go func() {
+		c <- h.Close()
+	}()
+
+	select {
+	case err := <-c:
+		if err == nil {
+			l.Critical("Close should fail after the timeout")
+			t.Fail()
+		}
+	case <-time.After(5 * time.Second):
+		l.Critical("Close did not give up after the timeout")
+		t.Fail()
+	}
+}
@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Output is a destination for the messages of a logger. Every output has
+// its own encoder and only receives messages with a priority between
+// MinPriority and MaxPriority. A nil Encoder formats the messages with the
+// Format and TimeFormat of the logger. If Collapser is set identical
+// records are collapsed into a summary.
+//
+// Outputs of the registered loggers which use the same writer share one
+// lock and one counter of failed writes. Writers are the same if they
+// are equal, which is why they should be pointers. Writers whose type is
+// not comparable get their own lock for every output and can not be
+// removed with RemoveOutput.
+type Output struct {
+	Writer      io.Writer
+	Encoder     Encoder
+	MinPriority Priority
+	MaxPriority Priority
+	NoColor     bool
+	Collapser   *Collapser
+
+	state *writer
+}
+
+// NewOutput returns an Output for the given io.Writer which receives all
+// messages of the logger and formats them with the format of the logger.
+func NewOutput(wr io.Writer) Output {
+	return Output{
+		Writer:      wr,
+		MinPriority: Trace,
+		MaxPriority: Emergency,
+	}
+}
+
+func (ou *Output) check() (err error) {
+	if ou.Writer == nil {
+		err = errors.New("the output writer is nil")
+		return
+	}
+
+	err = checkPriority(ou.MinPriority)
+	if err != nil {
+		return
+	}
+
+	err = checkPriority(ou.MaxPriority)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (ou *Output) accepts(pr Priority) bool {
+	return pr >= ou.MinPriority && pr <= ou.MaxPriority
+}
+
+func (ou *Output) encode(b []byte, re *Record, lo logger) []byte {
+	nc := lo.NoColor || ou.NoColor
+
+	if ou.Encoder == nil {
+		return appendMessage(b, re, lo.Format, lo.TimeFormat, nc, lo.Sanitize)
+	}
+
+	return ou.Encoder.Encode(b, re, nc)
+}
+
+// same returns true if both values are the same. Values which can not
+// be compared are never the same.
+func same(a, b interface{}) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+
+	if !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+
+	return a == b
+}
+
+func contains(li []interface{}, va interface{}) bool {
+	for _, v := range li {
+		if same(v, va) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writer holds the state of an io.Writer used by outputs.
+type writer struct {
+	mutex  sync.Mutex
+	failed atomic.Uint64
+}
+
+// comparable returns true if the writer can be used as a map key.
+func comparable(wr io.Writer) bool {
+	return wr != nil && reflect.TypeOf(wr).Comparable()
+}
+
+// writerState returns the state of the writer of the output. Outputs
+// which were not added to a logger use the state of the registry if the
+// writer is used by a logger.
+func (ou *Output) writerState() *writer {
+	if ou.state != nil {
+		return ou.state
+	}
+
+	w := list.lookupWriter(ou.Writer)
+	if w == nil {
+		w = new(writer)
+	}
+
+	return w
+}
+
+// writeOutput writes the line to the writer while holding the lock of
+// its state so lines from different goroutines never interleave. Failed
+// writes are counted in the state.
+func writeOutput(w *writer, wr io.Writer, li []byte) error {
+	w.mutex.Lock()
+	n, err := wr.Write(li)
+	w.mutex.Unlock()
+
+	if err == nil && n != len(li) {
+		err = io.ErrShortWrite
+	}
+
+	if err != nil {
+		w.failed.Add(1)
+	}
+
+	return err
+}
+
+// FailedWrites returns the number of writes to the given io.Writer which
+// returned an error. Only writers which are used by an output of a logger
+// or as the fallback output are counted.
+func FailedWrites(wr io.Writer) uint64 {
+	w := list.lookupWriter(wr)
+	if w == nil {
+		return 0
+	}
+
+	return w.failed.Load()
+}
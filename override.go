@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+)
+
+// DefaultLevelHeader is the HTTP header used by LevelHeaderMiddleware if
+// no header is given.
+const DefaultLevelHeader = "X-Log-Level"
+
+// LevelOverride defines which loggers can have their priority level
+// overridden per request with WithLevel. A logger can be overridden if
+// it or one of its parents is in the list of loggers. The level can not
+// be lowered below MaxVerbosity so a single request can not flood the
+// system.
+type LevelOverride struct {
+	Loggers      []Logger
+	MaxVerbosity Priority
+}
+
+type leveloverride struct {
+	loggers      map[Logger]bool
+	maxverbosity Priority
+}
+
+// SetLevelOverride sets which loggers can be overridden per request. By
+// default no logger can be overridden and levels carried by contexts are
+// ignored. Setting nil disables the override again.
+func SetLevelOverride(ov *LevelOverride) error {
+	return list.SetLevelOverride(ov)
+}
+
+func (lo *loggers) SetLevelOverride(ov *LevelOverride) (err error) {
+	if ov == nil {
+		lo.override.Store(nil)
+		return
+	}
+
+	err = checkPriority(ov.MaxVerbosity)
+	if err != nil {
+		return
+	}
+
+	o := &leveloverride{
+		loggers:      make(map[Logger]bool, len(ov.Loggers)),
+		maxverbosity: ov.MaxVerbosity,
+	}
+
+	for _, l := range ov.Loggers {
+		o.loggers[l] = true
+	}
+
+	lo.override.Store(o)
+
+	return
+}
+
+// EnabledCtx returns true if a message with the given priority would be
+// printed by the given logger with the context. Unlike Enabled it takes
+// a level carried by the context into account, see WithLevel.
+func EnabledCtx(ctx context.Context, lo Logger, pr Priority) bool {
+	l := list.GetLogger(lo)
+
+	return list.level(ctx, l) <= pr
+}
+
+// EnabledCtx returns true if a message with the given priority would be
+// printed by the Logger with the context.
+func (lo Logger) EnabledCtx(ctx context.Context, pr Priority) bool {
+	return EnabledCtx(ctx, lo, pr)
+}
+
+// level returns the priority level of the logger for the context.
+func (lo *loggers) level(ctx context.Context, lg logger) Priority {
+	p, ok := ctx.Value(contextlevel).(Priority)
+	if !ok || p >= lg.Priority {
+		return lg.Priority
+	}
+
+	o := lo.override.Load()
+	if o == nil || !o.allowed(lg.Logger) {
+		return lg.Priority
+	}
+
+	if p < o.maxverbosity {
+		p = o.maxverbosity
+	}
+
+	return p
+}
+
+func (ov *leveloverride) allowed(lo Logger) bool {
+	for {
+		if ov.loggers[lo] {
+			return true
+		}
+
+		if lo == defroot {
+			return false
+		}
+
+		lo = getParent(lo)
+	}
+}
+
+// WithLevel returns a copy of the context which carries the priority
+// level. Messages logged with the context use this level instead of the
+// level of their logger if the logger is allowed by SetLevelOverride.
+// The level can only make loggers more verbose.
+func WithLevel(ctx context.Context, pr Priority) context.Context {
+	return context.WithValue(ctx, contextlevel, pr)
+}
+
+// LevelHeaderMiddleware returns a http.Handler which reads a priority
+// level from the given header of every request and carries it in the
+// context of the request with WithLevel. An empty header uses
+// DefaultLevelHeader. Requests without the header or with an unknown
+// level are passed on unchanged.
+func LevelHeaderMiddleware(he string, ne http.Handler) http.Handler {
+	if he == "" {
+		he = DefaultLevelHeader
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := r.Header.Get(he)
+		if v == "" {
+			ne.ServeHTTP(w, r)
+			return
+		}
+
+		p, err := ParsePriority(v)
+		if err != nil {
+			ne.ServeHTTP(w, r)
+			return
+		}
+
+		ne.ServeHTTP(w, r.WithContext(WithLevel(r.Context(), p)))
+	})
+}
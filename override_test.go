@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLevelOverride(t *testing.T) {
+	l := New(namet + ".LevelOverride")
+
+	a := New(namet + ".LevelOverride.Allowed")
+	a.SetLevel(Warning)
+	a.SetFormat("{{.Message}},")
+
+	d := New(namet + ".LevelOverride.Denied")
+	d.SetLevel(Warning)
+	d.SetFormat("{{.Message}},")
+
+	var b bytes.Buffer
+	a.SetOutput(&b)
+	d.SetOutput(&b)
+
+	c := WithLevel(context.Background(), Trace)
+
+	a.DebugCtx(c, "Ignored")
+
+	SetLevelOverride(&LevelOverride{
+		Loggers:      []Logger{a},
+		MaxVerbosity: Debug,
+	})
+	defer SetLevelOverride(nil)
+
+	a.DebugCtx(c, "Debug")
+	a.TraceCtx(c, "Trace")
+	New(string(a), "Child").InfoCtx(c, "Child")
+	a.Debug("Without context")
+	d.DebugCtx(c, "Denied")
+	a.InfoCtx(WithLevel(context.Background(), Error), "Not raised")
+
+	o := b.String()
+	v := "Debug,Child,"
+	if o != v {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+}
+
+func TestEnabledCtx(t *testing.T) {
+	l := New(namet + ".EnabledCtx")
+
+	n := New(namet + ".EnabledCtx.Test")
+	n.SetLevel(Warning)
+
+	c := WithLevel(context.Background(), Trace)
+
+	if n.EnabledCtx(c, Debug) {
+		l.Critical("GOT: true, EXPECTED: false without override")
+		t.Fail()
+	}
+
+	SetLevelOverride(&LevelOverride{
+		Loggers:      []Logger{n},
+		MaxVerbosity: Debug,
+	})
+	defer SetLevelOverride(nil)
+
+	for k, v := range map[Priority]bool{
+		Trace:   false,
+		Debug:   true,
+		Warning: true,
+	} {
+		o := n.EnabledCtx(c, k)
+		if o != v {
+			l.Critical("GOT: ", o, ", EXPECTED: ", v, " for ", k)
+			t.Fail()
+		}
+	}
+
+	if n.Enabled(Debug) {
+		l.Critical("GOT: true, EXPECTED: false without context")
+		t.Fail()
+	}
+}
+
+func TestLevelHeaderMiddleware(t *testing.T) {
+	l := New(namet + ".LevelHeaderMiddleware")
+
+	n := New(namet + ".LevelHeaderMiddleware.Test")
+	n.SetLevel(Warning)
+	n.SetFormat("{{.Message}},")
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+
+	SetLevelOverride(&LevelOverride{
+		Loggers:      []Logger{n},
+		MaxVerbosity: Trace,
+	})
+	defer SetLevelOverride(nil)
+
+	h := LevelHeaderMiddleware("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.DebugCtx(r.Context(), r.Header.Get(DefaultLevelHeader))
+	}))
+
+	for _, v := range []string{"", "Debug", "Unknown"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		if v != "" {
+			r.Header.Set(DefaultLevelHeader, v)
+		}
+
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	o := b.String()
+	v := "Debug,"
+	if o != v {
+		l.Critical("GOT: ", o, ", EXPECTED: ", v)
+		t.Fail()
+	}
+}
@@ -2,17 +2,169 @@ package logger
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
+// Buffers bigger than this will not be put back into the pool so a
+// single huge message does not pin its memory forever.
+const maxbuffersize = 64 << 10
+
+var buffers = sync.Pool{
+	New: func() interface{} {
+		return new(buffer)
+	},
+}
+
+// buffer holds the record, the message text and the finished line while
+// a message is printed. Buffers are reused through a sync.Pool so
+// printing a message does not allocate in the common case.
+type buffer struct {
+	Record
+	text []byte
+	line []byte
+}
+
+func getBuffer() *buffer {
+	return buffers.Get().(*buffer)
+}
+
+func putBuffer(bu *buffer) {
+	if cap(bu.line) > maxbuffersize || cap(bu.text) > maxbuffersize {
+		return
+	}
+
+	bu.Record = Record{}
+	bu.text = bu.text[:0]
+	bu.line = bu.line[:0]
+
+	buffers.Put(bu)
+}
+
+// Write appends to the message text so the buffer can be used with the
+// fmt.Fprint family.
+func (bu *buffer) Write(p []byte) (int, error) {
+	bu.text = append(bu.text, p...)
+
+	return len(p), nil
+}
+
 func printMessage(lo logger, pr Priority, me ...interface{}) {
-	m := new(message)
-	m.Time = time.Now().Format(string(lo.TimeFormat))
-	m.Logger = lo.Logger
-	m.Priority = formatPriority(pr, lo.NoColor)
-	m.Message = fmt.Sprint(me...)
+	printFields(lo, pr, TraceContext{}, nil, me...)
+}
+
+// printFields prints the message with the given trace context and
+// fields. The fields are used by the record as they are so they must not
+// be changed afterwards.
+func printFields(lo logger, pr Priority, tc TraceContext, fi []Field, me ...interface{}) {
+	b := getBuffer()
+	b.TraceID = tc.TraceID
+	b.SpanID = tc.SpanID
+	b.Fields = fi
+
+	if len(me) == 1 {
+		if s, ok := me[0].(string); ok {
+			b.Message = s
+			writeMessage(lo, pr, b)
+			return
+		}
+	}
+
+	fmt.Fprint(b, me...)
+	b.Message = string(b.text)
+
+	writeMessage(lo, pr, b)
+}
+
+func printString(lo logger, pr Priority, me string) {
+	b := getBuffer()
+	b.Message = me
+
+	writeMessage(lo, pr, b)
+}
+
+// writeMessage runs the middleware on the record in the buffer, checks
+// it against the filters and the sampler of the logger and passes it to
+// every output and handler of the logger which accepts its priority.
+// Every output gets the encoded line with a single Write call. If the
+// logger propagates messages the outputs and handlers of its ancestors
+// are used as well. The buffer is put back into the pool afterwards.
+func writeMessage(lo logger, pr Priority, b *buffer) {
+	b.Time = time.Now()
+	b.Logger = lo.Logger
+	b.Priority = pr
+
+	list.runMiddleware(lo, &b.Record)
+
+	if !checkFilters(lo.Filters, b.Record) {
+		putBuffer(b)
+		return
+	}
+
+	s := lo.Samplers[b.Priority]
+	if s != nil && !s.Sample(&b.Record) {
+		putBuffer(b)
+		return
+	}
+
+	var u [8]interface{}
+	d := u[:0]
+
+	l := lo
+	for {
+		d = writeOutputs(l, b, d)
+
+		if !l.Propagate || l.Logger == defroot {
+			break
+		}
+
+		l = list.GetLogger(getParent(l.Logger))
+	}
+
+	putBuffer(b)
+}
+
+// writeOutputs writes the record to every output and handler of the
+// logger which accepts it and which is not in the list of already used
+// writers and handlers. The writers and handlers used are appended to
+// the list.
+func writeOutputs(lo logger, b *buffer, us []interface{}) []interface{} {
+	for i := range lo.Outputs {
+		o := &lo.Outputs[i]
+		if !o.accepts(b.Priority) || contains(us, o.Writer) {
+			continue
+		}
+
+		us = append(us, o.Writer)
+
+		if o.Collapser != nil && !o.Collapser.pass(lo, o, &b.Record) {
+			continue
+		}
+
+		b.line = o.encode(b.line[:0], &b.Record, lo)
+
+		err := writeOutput(o.writerState(), o.Writer, b.line)
+		if err != nil {
+			list.handleError(lo, b.line, err)
+		}
+	}
+
+	for _, h := range lo.Handlers {
+		if contains(us, h) {
+			continue
+		}
+
+		us = append(us, h)
+
+		if !h.Enabled(b.Logger, b.Priority) {
+			continue
+		}
 
-	s := formatMessage(m, lo.Format)
+		err := h.Handle(b.Record)
+		if err != nil {
+			list.handleError(lo, nil, err)
+		}
+	}
 
-	fmt.Fprint(lo.Output, s)
+	return us
 }
@@ -0,0 +1,7 @@
+//go:build race
+
+package logger
+
+// The race detector makes sync.Pool drop items on purpose so allocation
+// counts can not be checked when it is enabled.
+const raceenabled = true
@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults of a RateLimiter returned by NewRateLimiter.
+const (
+	DefaultRateInterval = 10 * time.Second
+	DefaultRateExempt   = Alert
+)
+
+// RateLimiter limits the number of messages a logger prints with a token
+// bucket. Every logger using the limiter, including child loggers which
+// inherited it, gets its own bucket. If PerCallSite is true every place
+// in the code which logs gets its own bucket too.
+//
+// Messages which are dropped are counted and a summary is printed with
+// the highest dropped priority once Interval passed since the first
+// dropped message. Messages with a priority of Exempt or above are never
+// dropped. A zero Interval uses DefaultRateInterval and a zero Exempt uses
+// DefaultRateExempt, so Emergency and Alert are exempt unless Exempt is
+// set.
+//
+// The fields must not be changed after the limiter was passed to
+// SetRateLimiter.
+type RateLimiter struct {
+	Rate        float64
+	Burst       int
+	PerCallSite bool
+	Interval    time.Duration
+	Exempt      Priority
+
+	mutex   sync.Mutex
+	buckets map[ratekey]*bucket
+}
+
+type ratekey struct {
+	logger Logger
+	pc     uintptr
+}
+
+type bucket struct {
+	tokens     float64
+	last       time.Time
+	suppressed uint64
+	priority   Priority
+}
+
+// NewRateLimiter returns a RateLimiter which allows rate messages per
+// second with bursts of up to burst messages. Summaries are printed
+// every DefaultRateInterval and messages with DefaultRateExempt or above
+// are never dropped.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		Rate:     rate,
+		Burst:    burst,
+		Interval: DefaultRateInterval,
+		Exempt:   DefaultRateExempt,
+	}
+}
+
+func (rl *RateLimiter) interval() time.Duration {
+	if rl.Interval <= 0 {
+		return DefaultRateInterval
+	}
+
+	return rl.Interval
+}
+
+func (rl *RateLimiter) exempt() Priority {
+	if rl.Exempt == Trace {
+		return DefaultRateExempt
+	}
+
+	return rl.Exempt
+}
+
+// allow returns true if the message of the logger can be printed. sk is
+// the number of stack frames to skip to get to the call site.
+func (rl *RateLimiter) allow(lo Logger, pr Priority, sk int) bool {
+	if pr >= rl.exempt() {
+		return true
+	}
+
+	k := ratekey{logger: lo}
+	if rl.PerCallSite {
+		var p [1]uintptr
+		runtime.Callers(sk+1, p[:])
+		k.pc = p[0]
+	}
+
+	n := time.Now()
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if rl.buckets == nil {
+		rl.buckets = make(map[ratekey]*bucket)
+	}
+
+	b, x := rl.buckets[k]
+	if !x {
+		b = &bucket{tokens: float64(rl.Burst), last: n}
+		rl.buckets[k] = b
+	}
+
+	b.tokens += n.Sub(b.last).Seconds() * rl.Rate
+	if b.tokens > float64(rl.Burst) {
+		b.tokens = float64(rl.Burst)
+	}
+	b.last = n
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+
+	if b.suppressed == 0 {
+		b.priority = pr
+		time.AfterFunc(rl.interval(), func() {
+			rl.summary(k)
+		})
+	}
+
+	b.suppressed++
+	if pr > b.priority {
+		b.priority = pr
+	}
+
+	return false
+}
+
+// summary prints how many messages were dropped for the key since the
+// last summary.
+func (rl *RateLimiter) summary(k ratekey) {
+	rl.mutex.Lock()
+	b := rl.buckets[k]
+	c := b.suppressed
+	p := b.priority
+	b.suppressed = 0
+	rl.mutex.Unlock()
+
+	if c == 0 {
+		return
+	}
+
+	m := "suppressed " + strconv.FormatUint(c, 10) + " messages from " + string(k.logger)
+	if k.pc != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{k.pc}).Next()
+		m += " at " + f.File + ":" + strconv.Itoa(f.Line)
+	}
+	m += " in the last " + rl.interval().String()
+
+	printString(list.GetLogger(k.logger), p, m)
+}
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordHandler struct {
+	mutex   sync.Mutex
+	records []Record
+}
+
+func (re *recordHandler) Enabled(lo Logger, pr Priority) bool {
+	return true
+}
+
+func (re *recordHandler) Handle(rc Record) error {
+	re.mutex.Lock()
+	re.records = append(re.records, rc)
+	re.mutex.Unlock()
+
+	return nil
+}
+
+func (re *recordHandler) Records() []Record {
+	re.mutex.Lock()
+	defer re.mutex.Unlock()
+
+	return append([]Record(nil), re.records...)
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := New(namet + ".RateLimiter")
+
+	n := New(namet + ".RateLimiter.Test")
+	n.SetOutput(ioutil.Discard)
+
+	h := new(recordHandler)
+	n.AddHandler(h)
+
+	r := NewRateLimiter(0.001, 2)
+	r.Interval = 50 * time.Millisecond
+	n.SetRateLimiter(r)
+
+	for i := 0; i < 10; i++ {
+		n.Warning("Test")
+	}
+	n.Error("Test")
+	n.Alert("Alert")
+
+	o := h.Records()
+	if len(o) != 3 {
+		l.Critical("GOT: ", len(o), " records, EXPECTED: 3")
+		t.FailNow()
+	}
+
+	if o[2].Priority != Alert {
+		l.Critical("Alert should not be limited")
+		t.Fail()
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	o = h.Records()
+	if len(o) != 4 {
+		l.Critical("GOT: ", len(o), " records, EXPECTED: 4")
+		t.FailNow()
+	}
+
+	v := "suppressed 9 messages from " + string(n) + " in the last 50ms"
+	if o[3].Message != v || o[3].Priority != Error {
+		l.Critical("GOT: ", o[3].Priority, " ", o[3].Message, ", EXPECTED: Error ", v)
+		t.Fail()
+	}
+}
+
+func TestRateLimiterZero(t *testing.T) {
+	l := New(namet + ".RateLimiterZero")
+
+	n := New(namet + ".RateLimiterZero.Test")
+	n.SetOutput(ioutil.Discard)
+
+	h := new(recordHandler)
+	n.AddHandler(h)
+
+	n.SetRateLimiter(&RateLimiter{Rate: 0.001, Burst: 1})
+
+	for i := 0; i < 10; i++ {
+		n.Warning("Test")
+	}
+	n.Alert("Alert")
+
+	o := h.Records()
+	if len(o) != 2 || o[1].Priority != Alert {
+		l.Critical("GOT: ", len(o), " records, EXPECTED: 2 with an Alert")
+		t.Fail()
+	}
+}
+
+func TestRateLimiterPerCallSite(t *testing.T) {
+	l := New(namet + ".RateLimiter.PerCallSite")
+
+	n := New(namet + ".RateLimiter.PerCallSite.Test")
+	n.SetOutput(ioutil.Discard)
+
+	h := new(recordHandler)
+	n.AddHandler(h)
+
+	r := NewRateLimiter(0.001, 1)
+	r.PerCallSite = true
+	r.Interval = 50 * time.Millisecond
+	n.SetRateLimiter(r)
+
+	for i := 0; i < 5; i++ {
+		n.Notice("First")
+		n.Notice("Second")
+	}
+
+	o := h.Records()
+	if len(o) != 2 {
+		l.Critical("GOT: ", len(o), " records, EXPECTED: 2")
+		t.FailNow()
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	o = h.Records()
+	if len(o) != 4 {
+		l.Critical("GOT: ", len(o), " records, EXPECTED: 4")
+		t.FailNow()
+	}
+
+	for _, v := range o[2:] {
+		if !strings.Contains(v.Message, "suppressed 4 messages") || !strings.Contains(v.Message, "ratelimit_test.go:") {
+			l.Critical("Wrong summary: ", v.Message)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"time"
+)
+
+// Field is a key value pair which adds structured data to a Record.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Record is a single message of a logger as it is passed to filters,
+// encoders and handlers.
+type Record struct {
+	Time     time.Time
+	Logger   Logger
+	Priority Priority
+	Message  string
+	TraceID  string
+	SpanID   string
+	Fields   []Field
+}
+
+// traceField returns true if the field is a trace id field which is
+// replaced by the trace context of the record. Encoders which write the
+// trace context as its own key skip these fields so the key is unique.
+func traceField(re *Record, fi Field) bool {
+	return re.TraceID != "" && fi.Key == FieldTraceID
+}
+
+// Handler receives the records of the loggers it was added to. Enabled
+// is called before Handle and Handle is only called if Enabled returned
+// true. Handlers can be used as sinks, filters or to encode records in
+// their own way.
+//
+// A Handler can be called concurrently and has to be safe for concurrent
+// use. It may keep the Record, records are never modified after they
+// were passed to a handler.
+type Handler interface {
+	Enabled(lo Logger, pr Priority) bool
+	Handle(re Record) error
+}
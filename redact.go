@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultMask is the mask which replaces secrets found by a Redactor.
+const DefaultMask = "[REDACTED]"
+
+var (
+	redactauthorization = regexp.MustCompile(`(?i)(\bauthorization["']?\s*[:=]\s*["']?(?:(?:bearer|basic|digest|token)\s+)?)[^\s,;"']+`)
+	redactpassword      = regexp.MustCompile(`(?i)(\b(?:\w*(?:password|passwd|secret|token)|pwd|api[_-]?key)["']?\s*[:=]\s*["']?)[^\s&,;"']+`)
+	redactawskey        = regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)
+	redactawssecret     = regexp.MustCompile(`(?i)(\baws_secret_access_key\s*[:=]\s*)[A-Za-z0-9/+=]{40}`)
+	redactjwt           = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	redactcard          = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+	// redactfields are the names of the fields which are masked by
+	// default. Fields whose names end with one of them are masked too, for
+	// example access_token or client_secret.
+	redactfields = []string{
+		"authorization",
+		"password",
+		"passwd",
+		"pwd",
+		"secret",
+		"token",
+		"api_key",
+		"api-key",
+		"apikey",
+	}
+)
+
+// Redactor replaces secrets in the message and the fields of records
+// with a mask. A Redactor returned by NewRedactor detects authorization
+// headers, password pairs, AWS access keys, JWTs and credit card numbers
+// which pass the Luhn check, also inside JSON, and masks fields like
+// password or access_token. More expressions and field names can be
+// added.
+//
+// A Redactor must not be changed after it was passed to SetRedactor.
+type Redactor struct {
+	// Mask replaces every secret which was found.
+	Mask string
+
+	patterns []*regexp.Regexp
+	fields   map[string]bool
+	suffixes []string
+	cards    bool
+}
+
+// NewRedactor returns a Redactor with the built-in detectors and the
+// DefaultMask.
+func NewRedactor() *Redactor {
+	r := &Redactor{
+		Mask: DefaultMask,
+		patterns: []*regexp.Regexp{
+			redactauthorization,
+			redactpassword,
+			redactawskey,
+			redactawssecret,
+			redactjwt,
+		},
+		fields:   make(map[string]bool),
+		suffixes: redactfields,
+		cards:    true,
+	}
+
+	return r
+}
+
+// AddPattern adds the regular expression to the expressions of the
+// Redactor. Every match is replaced by the mask. If the expression has
+// capturing groups the first group is kept in front of the mask, for
+// example the key of a key value pair.
+func (rd *Redactor) AddPattern(ex *regexp.Regexp) {
+	rd.patterns = append(rd.patterns, ex)
+}
+
+// AddField adds the field name to the names of fields whose values are
+// always replaced by the mask. Field names are compared case
+// insensitive.
+func (rd *Redactor) AddField(key string) {
+	if rd.fields == nil {
+		rd.fields = make(map[string]bool)
+	}
+
+	rd.fields[strings.ToLower(key)] = true
+}
+
+// Redact returns the string with all secrets found replaced by the mask.
+func (rd *Redactor) Redact(s string) string {
+	for _, p := range rd.patterns {
+		s = rd.replace(p, s)
+	}
+
+	if rd.cards {
+		s = redactcard.ReplaceAllStringFunc(s, func(c string) string {
+			if !checkLuhn(c) {
+				return c
+			}
+
+			return rd.Mask
+		})
+	}
+
+	return s
+}
+
+func (rd *Redactor) replace(ex *regexp.Regexp, s string) string {
+	if !ex.MatchString(s) {
+		return s
+	}
+
+	m := strings.ReplaceAll(rd.Mask, "$", "$$")
+	if ex.NumSubexp() > 0 {
+		m = "${1}" + m
+	}
+
+	return ex.ReplaceAllString(s, m)
+}
+
+// secretField returns true if the value of the field with the given name
+// is always masked.
+func (rd *Redactor) secretField(key string) bool {
+	k := strings.ToLower(key)
+	if rd.fields[k] {
+		return true
+	}
+
+	for _, v := range rd.suffixes {
+		if strings.HasSuffix(k, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactRecord redacts the message and the fields of the record. The
+// fields are copied before they are changed as they can be shared with
+// other records.
+func (rd *Redactor) redactRecord(rc *Record) {
+	rc.Message = rd.Redact(rc.Message)
+
+	var f []Field
+	for i, v := range rc.Fields {
+		n := v.Value
+		if rd.secretField(v.Key) {
+			n = rd.Mask
+		} else {
+			n = rd.Redact(n)
+		}
+
+		if n == v.Value {
+			continue
+		}
+
+		if f == nil {
+			f = make([]Field, len(rc.Fields))
+			copy(f, rc.Fields)
+		}
+
+		f[i].Value = n
+	}
+
+	if f != nil {
+		rc.Fields = f
+	}
+}
+
+// checkLuhn returns true if the digits in the string pass the Luhn
+// check. All other characters are ignored.
+func checkLuhn(s string) bool {
+	t := 0
+	d := false
+
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		n := int(c - '0')
+		if d {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+
+		t += n
+		d = !d
+	}
+
+	return t%10 == 0
+}
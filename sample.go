@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Sampler decides which records of a logger are kept. Records for which
+// Sample returns false are dropped. A Sampler can be called concurrently
+// and has to be safe for concurrent use.
+type Sampler interface {
+	Sample(re *Record) bool
+}
+
+const tickcounters = 4096
+
+// TickSampler keeps the first records with the same logger and message
+// every tick and after that every Thereafter-th record. This is the
+// sampling strategy of zap. Records are counted in a fixed number of
+// counters so unrelated records can share a counter.
+type TickSampler struct {
+	First      uint64
+	Thereafter uint64
+	Tick       time.Duration
+
+	mutex    sync.Mutex
+	counters [tickcounters]tickcounter
+}
+
+type tickcounter struct {
+	reset time.Time
+	count uint64
+}
+
+// NewTickSampler returns a TickSampler which keeps the first records per
+// second and every thereafter-th record after that.
+func NewTickSampler(first, thereafter uint64) *TickSampler {
+	return &TickSampler{
+		First:      first,
+		Thereafter: thereafter,
+		Tick:       time.Second,
+	}
+}
+
+// Sample implements the Sampler interface.
+func (ti *TickSampler) Sample(re *Record) bool {
+	h := fnv.New32a()
+	h.Write([]byte(re.Logger))
+	h.Write([]byte{0})
+	h.Write([]byte(re.Message))
+
+	ti.mutex.Lock()
+	c := &ti.counters[h.Sum32()%tickcounters]
+	if !re.Time.Before(c.reset) {
+		c.reset = re.Time.Add(ti.Tick)
+		c.count = 0
+	}
+	c.count++
+	n := c.count
+	ti.mutex.Unlock()
+
+	if n <= ti.First {
+		return true
+	}
+
+	return ti.Thereafter > 0 && (n-ti.First)%ti.Thereafter == 0
+}
+
+// RandomSampler keeps records randomly with the given rate between 0 and
+// 1.
+type RandomSampler struct {
+	Rate float64
+}
+
+// NewRandomSampler returns a RandomSampler with the given rate.
+func NewRandomSampler(rate float64) *RandomSampler {
+	return &RandomSampler{
+		Rate: rate,
+	}
+}
+
+// Sample implements the Sampler interface.
+func (ra *RandomSampler) Sample(re *Record) bool {
+	return rand.Float64() < ra.Rate
+}
+
+// HashSampler keeps records based on the hash of the value of a field,
+// for example a request id. All records with the same value are either
+// kept or dropped so all lines of a sampled request stay together. Rate
+// is the part of the values which is kept between 0 and 1. Records
+// without the field are always kept.
+type HashSampler struct {
+	Key  string
+	Rate float64
+}
+
+// NewHashSampler returns a HashSampler for the field with the given key
+// and rate.
+func NewHashSampler(key string, rate float64) *HashSampler {
+	return &HashSampler{
+		Key:  key,
+		Rate: rate,
+	}
+}
+
+// Sample implements the Sampler interface.
+func (ha *HashSampler) Sample(re *Record) bool {
+	for _, f := range re.Fields {
+		if f.Key != ha.Key {
+			continue
+		}
+
+		h := fnv.New64a()
+		h.Write([]byte(f.Value))
+
+		return float64(mixHash(h.Sum64())) < ha.Rate*math.MaxUint64
+	}
+
+	return true
+}
+
+// mixHash spreads the bits of the hash so short values which only differ
+// in a few bits are distributed evenly. This is the finalizer of
+// splitmix64.
+func mixHash(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+
+	return h
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"io/ioutil"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTickSampler(t *testing.T) {
+	l := New(namet + ".Sampler.Tick")
+
+	s := NewTickSampler(3, 10)
+
+	n := time.Now()
+	r := Record{Time: n, Logger: "Test", Message: "Test"}
+
+	c := 0
+	for i := 0; i < 100; i++ {
+		if s.Sample(&r) {
+			c++
+		}
+	}
+
+	// 3 first records and every 10th of the other 97.
+	if c != 12 {
+		l.Critical("GOT: ", c, " records, EXPECTED: 12")
+		t.Fail()
+	}
+
+	r.Time = n.Add(time.Second)
+	if !s.Sample(&r) {
+		l.Critical("Record after the tick should be kept")
+		t.Fail()
+	}
+}
+
+func TestRandomSampler(t *testing.T) {
+	l := New(namet + ".Sampler.Random")
+
+	m := map[float64]int{
+		0: 0,
+		1: 1000,
+	}
+
+	for k, v := range m {
+		s := NewRandomSampler(k)
+		r := Record{Message: "Test"}
+
+		c := 0
+		for i := 0; i < 1000; i++ {
+			if s.Sample(&r) {
+				c++
+			}
+		}
+
+		if c != v {
+			l.Critical("GOT: ", c, " records, EXPECTED: ", v, ", RATE: ", k)
+			t.Fail()
+		}
+	}
+}
+
+func TestHashSampler(t *testing.T) {
+	l := New(namet + ".Sampler.Hash")
+
+	n := New(namet + ".Sampler.Hash.Test")
+	n.SetLevel(Debug)
+	n.SetOutput(ioutil.Discard)
+
+	h := new(recordHandler)
+	n.AddHandler(h)
+
+	i := 0
+	n.AddMiddleware(func(re *Record) {
+		re.Fields = []Field{{Key: "request", Value: strconv.Itoa(i)}}
+	})
+	n.SetSampler(Debug, NewHashSampler("request", 0.5))
+
+	for i = 0; i < 1000; i++ {
+		n.Debug("First")
+		n.Debug("Second")
+		n.Info("Info")
+	}
+
+	k := make(map[string]int)
+	c := 0
+	for _, r := range h.Records() {
+		if r.Priority == Info {
+			c++
+			continue
+		}
+
+		k[r.Fields[0].Value]++
+	}
+
+	if c != 1000 {
+		l.Critical("GOT: ", c, " info records, EXPECTED: 1000")
+		t.Fail()
+	}
+
+	if len(k) < 400 || len(k) > 600 {
+		l.Critical("GOT: ", len(k), " sampled requests, EXPECTED: about 500")
+		t.Fail()
+	}
+
+	for r, v := range k {
+		if v != 2 {
+			l.Critical("GOT: ", v, " records for request ", r, ", EXPECTED: 2")
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TraceparentHeader is the HTTP header of the W3C trace context.
+const TraceparentHeader = "traceparent"
+
+// TraceContext identifies the span of a distributed trace as defined by
+// the W3C trace context. The ids are lower case hex strings, 32
+// characters for the trace id and 16 characters for the span ids.
+type TraceContext struct {
+	TraceID  string
+	SpanID   string
+	ParentID string
+	Sampled  bool
+}
+
+// ParseTraceparent parses the value of a traceparent header. The span id
+// of the header is returned as SpanID.
+func ParseTraceparent(tp string) (tc TraceContext, err error) {
+	p := strings.Split(strings.TrimSpace(tp), "-")
+	if len(p) < 4 {
+		err = errors.New("can not parse traceparent: wrong number of parts")
+		return
+	}
+
+	if len(p[0]) != 2 || !isHex(p[0]) || p[0] == "ff" {
+		err = errors.New("can not parse traceparent: invalid version " + p[0])
+		return
+	}
+
+	if p[0] == "00" && len(p) != 4 {
+		err = errors.New("can not parse traceparent: wrong number of parts")
+		return
+	}
+
+	if len(p[1]) != 32 || !isHex(p[1]) || p[1] == strings.Repeat("0", 32) {
+		err = errors.New("can not parse traceparent: invalid trace id " + p[1])
+		return
+	}
+
+	if len(p[2]) != 16 || !isHex(p[2]) || p[2] == strings.Repeat("0", 16) {
+		err = errors.New("can not parse traceparent: invalid span id " + p[2])
+		return
+	}
+
+	if len(p[3]) != 2 || !isHex(p[3]) {
+		err = errors.New("can not parse traceparent: invalid flags " + p[3])
+		return
+	}
+
+	f, _ := hex.DecodeString(p[3])
+
+	tc.TraceID = p[1]
+	tc.SpanID = p[2]
+	tc.Sampled = f[0]&1 == 1
+
+	return
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Traceparent returns the value of the traceparent header for the trace
+// context.
+func (tc TraceContext) Traceparent() string {
+	f := "00"
+	if tc.Sampled {
+		f = "01"
+	}
+
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + f
+}
+
+// Child returns a trace context for a new span of the same trace whose
+// parent is the span of the trace context.
+func (tc TraceContext) Child() TraceContext {
+	return TraceContext{
+		TraceID:  tc.TraceID,
+		SpanID:   newID(8),
+		ParentID: tc.SpanID,
+		Sampled:  tc.Sampled,
+	}
+}
+
+// NewTraceContext returns a trace context for the root span of a new
+// trace.
+func NewTraceContext() TraceContext {
+	return TraceContext{
+		TraceID: newID(16),
+		SpanID:  newID(8),
+		Sampled: true,
+	}
+}
+
+func newID(by int) string {
+	b := make([]byte, by)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// WithTraceContext returns a copy of the context which carries the trace
+// context. The trace and span id are added to every message logged with
+// the context.
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, contexttrace, tc)
+}
+
+// TraceFromContext returns the trace context carried by the context.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(contexttrace).(TraceContext)
+
+	return tc, ok
+}
+
+// TraceMiddleware returns a http.Handler which reads the traceparent
+// header of every request and carries a trace context for a new span of
+// that trace in the context of the request. Requests without a valid
+// header start a new trace. The traceparent of the new span is set on
+// the response.
+func TraceMiddleware(ne http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc, err := ParseTraceparent(r.Header.Get(TraceparentHeader))
+		if err != nil {
+			tc = NewTraceContext()
+		} else {
+			tc = tc.Child()
+		}
+
+		w.Header().Set(TraceparentHeader, tc.Traceparent())
+
+		ne.ServeHTTP(w, r.WithContext(WithTraceContext(r.Context(), tc)))
+	})
+}
+
+// Span is a lightweight span of a trace which logs when it starts and
+// ends.
+type Span struct {
+	Name     string
+	Logger   Logger
+	Priority Priority
+	Start    time.Time
+
+	context context.Context
+}
+
+// StartSpan starts a new span with the given name as a child of the span
+// carried by the context or as the root of a new trace. It logs the start
+// of the span with the Info priority and returns a context carrying the
+// new span which should be used for everything done in the span.
+func StartSpan(ctx context.Context, lo Logger, na string) (context.Context, *Span) {
+	tc, ok := TraceFromContext(ctx)
+	if ok {
+		tc = tc.Child()
+	} else {
+		tc = NewTraceContext()
+	}
+
+	c := WithTraceContext(ctx, tc)
+
+	s := &Span{
+		Name:     na,
+		Logger:   lo,
+		Priority: Info,
+		Start:    time.Now(),
+		context:  c,
+	}
+
+	logMessageCtx(c, lo, s.Priority, nil, "start ", na)
+
+	return c, s
+}
+
+// End logs the end of the span with its duration.
+func (sp *Span) End() {
+	d := time.Since(sp.Start)
+	f := []Field{{Key: FieldDuration, Value: d.String()}}
+
+	logMessageCtx(sp.context, sp.Logger, sp.Priority, f, "end ", sp.Name, " after ", d)
+}
@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	l := New(namet + ".ParseTraceparent")
+
+	v := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tc, err := ParseTraceparent(v)
+	if err != nil {
+		l.Critical("Can not parse traceparent: ", err)
+		t.FailNow()
+	}
+
+	if tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.SpanID != "00f067aa0ba902b7" || !tc.Sampled {
+		l.Critical("Wrong trace context: ", tc)
+		t.Fail()
+	}
+
+	if tc.Traceparent() != v {
+		l.Critical("GOT: ", tc.Traceparent(), ", EXPECTED: ", v)
+		t.Fail()
+	}
+
+	f := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-00",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x",
+	}
+
+	for _, k := range f {
+		_, err := ParseTraceparent(k)
+		if err == nil {
+			l.Critical("Parsing ", k, " should have failed")
+			t.Fail()
+		}
+	}
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	l := New(namet + ".TraceMiddleware")
+
+	n := New(namet + ".TraceMiddleware.Test")
+	n.SetLevel(Info)
+	n.SetFormat("{{.TraceID}} {{.SpanID}} {{.Message}}{{.Fields}}\n")
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+
+	var p TraceContext
+	h := TraceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ = TraceFromContext(r.Context())
+
+		c, s := StartSpan(r.Context(), n, "query")
+		n.NoticeCtx(c, "Test")
+		s.End()
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if p.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || p.ParentID != "00f067aa0ba902b7" {
+		l.Critical("Wrong trace context: ", p)
+		t.Fail()
+	}
+
+	if w.Header().Get(TraceparentHeader) != p.Traceparent() {
+		l.Critical("Wrong traceparent in response: ", w.Header().Get(TraceparentHeader))
+		t.Fail()
+	}
+
+	o := strings.Split(b.String(), "\n")
+	v := []*regexp.Regexp{
+		regexp.MustCompile(`^4bf92f3577b34da6a3ce929d0e0e4736 ([0-9a-f]{16}) start query$`),
+		regexp.MustCompile(`^4bf92f3577b34da6a3ce929d0e0e4736 ([0-9a-f]{16}) Test$`),
+		regexp.MustCompile(`^4bf92f3577b34da6a3ce929d0e0e4736 ([0-9a-f]{16}) end query after \S+ duration=\S+$`),
+	}
+
+	if len(o) != 4 {
+		l.Critical("GOT: ", b.String(), ", EXPECTED: 3 lines")
+		t.FailNow()
+	}
+
+	for i, e := range v {
+		m := e.FindStringSubmatch(o[i])
+		if m == nil {
+			l.Critical("GOT: ", o[i], ", EXPECTED: ", e)
+			t.Fail()
+			continue
+		}
+
+		if m[1] == p.SpanID {
+			l.Critical("Span should have its own span id")
+			t.Fail()
+		}
+	}
+}
+
+func TestTraceJSON(t *testing.T) {
+	l := New(namet + ".Trace.JSON")
+
+	n := New(namet + ".Trace.JSON.Test")
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+	n.RemoveOutput(&b)
+
+	o := NewOutput(&b)
+	o.Encoder = JSONEncoder{}
+	n.AddOutput(o)
+
+	tc := TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	n.NoticeCtx(WithTraceContext(context.Background(), tc), "Test")
+
+	v := `"message":"Test","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"}` + "\n"
+	if !strings.HasSuffix(b.String(), v) {
+		l.Critical("GOT: ", b.String(), ", EXPECTED suffix: ", v)
+		t.Fail()
+	}
+}
+
+func TestTraceDuplicate(t *testing.T) {
+	l := New(namet + ".Trace.Duplicate")
+
+	n := New(namet + ".Trace.Duplicate.Test")
+
+	var b bytes.Buffer
+	n.SetOutput(&b)
+	n.RemoveOutput(&b)
+
+	o := NewOutput(&b)
+	o.Encoder = JSONEncoder{}
+	n.AddOutput(o)
+
+	tc := TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	n.NoticeCtx(WithTraceContext(WithTraceID(context.Background(), "abc"), tc), "Test")
+
+	v := `"message":"Test","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"}` + "\n"
+	if !strings.HasSuffix(b.String(), v) || strings.Count(b.String(), "trace_id") != 1 {
+		l.Critical("GOT: ", b.String(), ", EXPECTED suffix: ", v)
+		t.Fail()
+	}
+}